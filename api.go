@@ -2,23 +2,49 @@ package healthcheck
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
-	"sync"
-	"time"
+	"slices"
 )
 
 // Register will register a check.
 //
 // All checks should have a name. Will be better that name will contain only lowercase symbols and lodash.
-// This is allowing to have the same name for Check and for metrics.
-func (s *Healthcheck) Register(ctx context.Context, check ICheck) {
+// This is allowing to have the same name for Check and for metrics. By default, the name will be normalized and
+// suffixed when it is duplicated. With WithStrictNames, Register returns ErrInvalidName or ErrDuplicateName instead.
+// Dependencies of the check (see WithDependsOn) should be registered before the check, otherwise Register returns
+// ErrCheckNotFound.
+//
+//	hc.Register(ctx, healthcheck.NewBasic("recommendations_cache", time.Second, pingCache), healthcheck.WithNonCritical())
+func (s *Healthcheck) Register(ctx context.Context, check ICheck, opts ...func(*checkOptions)) error {
+	options := defaultCheckOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if check, ok := check.(*bgCheck); ok && check.opts.initialRun {
+		// Do not run the check that will be rejected anyway.
+		s.checksMu.RLock()
+		err := s.validate(check, options)
+		s.checksMu.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		check.runInitial(ctx, s.opts.logger)
+	}
+
 	s.checksMu.Lock()
 	defer s.checksMu.Unlock()
 
-	checkID, ok := name2id(check.id())
+	if err := s.validate(check, options); err != nil {
+		return err
+	}
+
+	checkID, ok := name2id(check.ID())
 	if !ok {
 		s.opts.logger.WarnContext(ctx, "choose a better name for check. see docs of Register method",
-			slog.String("name", check.id()),
+			slog.String("name", check.ID()),
 			slog.String("better_name", checkID))
 	}
 
@@ -27,7 +53,7 @@ CheckID:
 		if s.checks[i].ID == checkID {
 			newID := checkID + "_x"
 			s.opts.logger.WarnContext(ctx, "check name is duplicated. add prefix",
-				slog.String("name", check.id()),
+				slog.String("name", check.ID()),
 				slog.String("new_name", newID))
 			checkID = newID
 
@@ -35,64 +61,200 @@ CheckID:
 		}
 	}
 
-	switch check := check.(type) {
-	case *bgCheck:
-		check.run(ctx)
-	}
+	s.setupHistory(check, options)
 
 	s.checks = append(s.checks, checkContainer{
 		ID:    checkID,
 		Check: check,
+		Opts:  options,
+		State: newCheckState(),
+		Stop:  s.startCheck(ctx, check),
 	})
+
+	return nil
 }
 
-// RunAllChecks will run all check immediately.
-func (s *Healthcheck) RunAllChecks(ctx context.Context) Report {
-	s.checksMu.RLock()
-	checksCopy := make([]checkContainer, len(s.checks))
-	copy(checksCopy, s.checks)
-	isShuttingDown := s.isShuttingDown
-	s.checksMu.RUnlock()
-
-	checks := make([]Check, len(checksCopy))
-	{
-		wg := new(sync.WaitGroup)
-		wg.Add(len(checksCopy))
-
-		// TODO(zhuravlev): do not run goroutines for checks like manual and bg check.
-		for i := range checksCopy {
-			go func(i int, check checkContainer) {
-				defer wg.Done()
-
-				checks[i] = s.runCheck(ctx, check)
-			}(i, checksCopy[i])
+// Unregister will stop the check and remove it from reports. id is the name of the check from the report. The check
+// can not be unregistered while other checks depend on it, see WithDependsOn.
+func (s *Healthcheck) Unregister(id string) error {
+	s.checksMu.Lock()
+	defer s.checksMu.Unlock()
+
+	idx := s.indexOf(id)
+	if idx == -1 {
+		return fmt.Errorf("unregister %q: %w", id, ErrCheckNotFound)
+	}
+
+	for i := range s.checks {
+		if slices.Contains(s.checks[i].Opts.dependsOn, id) {
+			return fmt.Errorf("unregister %q: required by %q: %w", id, s.checks[i].ID, ErrHasDependents)
 		}
+	}
 
-		wg.Wait()
-	}
-
-	if isShuttingDown {
-		checks = append(checks, Check{
-			Name: "__shutting_down__",
-			State: CheckState{
-				ActualAt: time.Now(),
-				Status:   StatusDown,
-				Error:    "The application in shutting down process",
-			},
-			Previous: nil,
-		})
-	}
-	status := StatusUp
-	for _, check := range checks {
-		if check.State.Status == StatusDown {
-			status = StatusDown
-			break
+	s.checks[idx].stop()
+	s.checks = slices.Delete(s.checks, idx, idx+1)
+
+	return nil
+}
+
+// Replace will atomically swap the implementation of the registered check. The check keeps its id and the options
+// that were passed to Register, but its reported state starts from scratch. The previous implementation is stopped.
+func (s *Healthcheck) Replace(ctx context.Context, id string, check ICheck) error {
+	if check, ok := check.(*bgCheck); ok && check.opts.initialRun {
+		// Do not run the check that will be rejected anyway.
+		s.checksMu.RLock()
+		idx := s.indexOf(id)
+		s.checksMu.RUnlock()
+		if idx == -1 {
+			return fmt.Errorf("replace %q: %w", id, ErrCheckNotFound)
 		}
+
+		check.runInitial(ctx, s.opts.logger)
+	}
+
+	s.checksMu.Lock()
+	defer s.checksMu.Unlock()
+
+	idx := s.indexOf(id)
+	if idx == -1 {
+		return fmt.Errorf("replace %q: %w", id, ErrCheckNotFound)
+	}
+
+	s.setupHistory(check, s.checks[idx].Opts)
+
+	prev := s.checks[idx]
+	s.checks[idx] = checkContainer{
+		ID:    prev.ID,
+		Check: check,
+		Opts:  prev.Opts,
+		State: newCheckState(),
+		Stop:  s.startCheck(ctx, check),
+	}
+	prev.stop()
+
+	return nil
+}
+
+// Trigger requests an immediate run of the background check. id is the name of the check from the report.
+func (s *Healthcheck) Trigger(id string) error {
+	check, err := s.getBackground(id)
+	if err != nil {
+		return fmt.Errorf("trigger %q: %w", id, err)
+	}
+
+	check.Trigger()
+
+	return nil
+}
+
+// Pause pauses the background check. id is the name of the check from the report. See bgCheck.Pause.
+func (s *Healthcheck) Pause(id, reason string) error {
+	check, err := s.getBackground(id)
+	if err != nil {
+		return fmt.Errorf("pause %q: %w", id, err)
+	}
+
+	check.Pause(reason)
+
+	return nil
+}
+
+// Resume resumes the paused background check. id is the name of the check from the report.
+func (s *Healthcheck) Resume(id string) error {
+	check, err := s.getBackground(id)
+	if err != nil {
+		return fmt.Errorf("resume %q: %w", id, err)
+	}
+
+	check.Resume()
+
+	return nil
+}
+
+// Start will start background checks that are not running. Background checks are started by Register, so it is
+// required only when WithManualStart is used or to start checks again after Close. The loops of checks will be
+// stopped when ctx is done or on Close.
+func (s *Healthcheck) Start(ctx context.Context) {
+	s.checksMu.Lock()
+	defer s.checksMu.Unlock()
+
+	s.isRunning = true
+	for i := range s.checks {
+		if s.checks[i].Stop == nil {
+			s.checks[i].Stop = s.startCheck(ctx, s.checks[i].Check)
+		}
+	}
+}
+
+// Close will stop all background checks and wait until running check functions return. Returns ctx.Err() when ctx
+// is done before that. Checks that are registered after Close will not be started until Start is called.
+func (s *Healthcheck) Close(ctx context.Context) error {
+	s.checksMu.Lock()
+	s.isRunning = false
+	for i := range s.checks {
+		s.checks[i].stop()
+	}
+	s.checksMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.bgWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+// RunAllChecks will run all check immediately. Use WithInclude and WithExclude to run only a part of checks.
+//
+// When WithSingleflight is enabled, concurrent callers with the same filter share one in-flight run and receive the
+// same Report. The shared run is not bound to ctx of any caller, and each caller receives the report with StatusDown
+// when its ctx is done before the shared run finishes.
+func (s *Healthcheck) RunAllChecks(ctx context.Context, opts ...RunOption) Report {
+	var options runOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if !s.opts.singleflight {
+		return s.runAllChecks(ctx, options)
+	}
+
+	key := options.key()
+
+	s.inflightMu.Lock()
+	run, ok := s.inflight[key]
+	if !ok {
+		run = &inflightRun{
+			done:   make(chan struct{}),
+			report: Report{Status: "", Checks: nil},
+		}
+		s.inflight[key] = run
+
+		// The run is shared with other callers, so cancellation of the first caller should not affect them.
+		go func(ctx context.Context) {
+			defer func() {
+				s.inflightMu.Lock()
+				delete(s.inflight, key)
+				s.inflightMu.Unlock()
+				close(run.done)
+			}()
+
+			run.report = s.runAllChecks(ctx, options)
+		}(context.WithoutCancel(ctx))
 	}
+	s.inflightMu.Unlock()
 
-	return Report{
-		Status: status,
-		Checks: checks,
+	select {
+	case <-ctx.Done():
+		return Report{Status: StatusDown, Checks: []Check{}}
+	case <-run.done:
+		return run.result()
 	}
 }
 
@@ -3,7 +3,13 @@ package healthcheck
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/kazhuravlev/healthcheck/internal/logr"
+	"github.com/kazhuravlev/just"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,49 +17,84 @@ var (
 	_ ICheck = (*basicCheck)(nil)
 	_ ICheck = (*manualCheck)(nil)
 	_ ICheck = (*bgCheck)(nil)
+
+	_ historyHolder = (*basicCheck)(nil)
+	_ historyHolder = (*manualCheck)(nil)
+	_ historyHolder = (*bgCheck)(nil)
+
+	_ scheduled = (*bgCheck)(nil)
 )
 
 // errInitial used as initial error for some checks.
 var errInitial = errors.New("initial")
 
+// errSkipped returned by background check when the run was skipped.
+var errSkipped = errors.New("skipped")
+
 type basicCheck struct {
 	name string
 	ttl  time.Duration
 	fn   CheckFn
 	logg *logr.Ring
+	opts basicOptions
 }
 
 // NewBasic creates a basic check. This check will only be performed when RunAllChecks is called.
 //
 //	hc, _ := healthcheck.New(...)
 //	hc.Register(healthcheck.NewBasic("postgres", time.Second, func(context.Context) error { ... }))
-func NewBasic(name string, timeout time.Duration, fn CheckFn) *basicCheck {
+func NewBasic(name string, timeout time.Duration, fn CheckFn, opts ...func(*basicOptions)) *basicCheck {
+	options := basicOptions{
+		cacheTTL: 0,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return &basicCheck{
 		name: name,
 		ttl:  timeout,
 		fn:   fn,
 		logg: logr.New(),
+		opts: options,
 	}
 }
 
-func (c *basicCheck) id() string             { return c.name }
-func (c *basicCheck) timeout() time.Duration { return c.ttl }
-func (c *basicCheck) check(ctx context.Context) logr.Rec {
+func (c *basicCheck) ID() string             { return c.name }
+func (c *basicCheck) Timeout() time.Duration { return c.ttl }
+func (c *basicCheck) Check(ctx context.Context) Rec {
+	if c.opts.cacheTTL > 0 {
+		if rec, ok := c.logg.GetLast(); ok && time.Since(rec.Time) < c.opts.cacheTTL {
+			rec.Cached = true
+
+			return newRec(rec)
+		}
+	}
+
+	start := time.Now()
+	details, err := splitDetails(callSafe(ctx, c.fn))
+
 	res := logr.Rec{
-		Time:  time.Now(),
-		Error: c.fn(ctx),
+		Time:     start,
+		Error:    err,
+		Duration: time.Since(start),
+		Details:  details,
 	}
-	c.logg.Put(res)
 
-	return res
+	return newRec(c.logg.Put(res))
 }
-func (c *basicCheck) log() []logr.Rec {
-	return c.logg.SlicePrev()
+func (c *basicCheck) Log() []Rec {
+	return just.SliceMap(c.logg.SlicePrev(), newRec)
 }
+func (c *basicCheck) history() *logr.Ring { return c.logg }
 
 type manualCheck struct {
 	name string
 	logg *logr.Ring
+	opts manualOptions
+
+	beatMu *sync.RWMutex
+	beatAt time.Time
 }
 
 // NewManual create new check, that can be managed by client. Marked as failed by default.
@@ -63,10 +104,21 @@ type manualCheck struct {
 //	check.SetError(nil)
 //	hc.Register(check)
 //	check.SetError(errors.New("service unavailable"))
-func NewManual(name string) *manualCheck {
+func NewManual(name string, opts ...func(*manualOptions)) *manualCheck {
+	options := manualOptions{
+		heartbeatTTL: 0,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	check := &manualCheck{
 		name: name,
 		logg: logr.New(),
+		opts: options,
+
+		beatMu: new(sync.RWMutex),
+		beatAt: time.Time{},
 	}
 
 	check.SetErr(errInitial)
@@ -75,25 +127,56 @@ func NewManual(name string) *manualCheck {
 }
 
 func (c *manualCheck) SetErr(err error) {
+	now := time.Now()
+	details, err := splitDetails(err)
 	c.logg.Put(logr.Rec{
-		Time:  time.Now(),
-		Error: err,
+		Time:    now,
+		Error:   err,
+		Details: details,
 	})
+	c.setBeat(now)
+}
+
+// Beat tells the check that the client is alive without changing the error of the check. See WithHeartbeatTTL.
+func (c *manualCheck) Beat() {
+	c.setBeat(time.Now())
+}
+
+func (c *manualCheck) setBeat(t time.Time) {
+	c.beatMu.Lock()
+	defer c.beatMu.Unlock()
+
+	c.beatAt = t
 }
 
-func (c *manualCheck) id() string             { return c.name }
-func (c *manualCheck) timeout() time.Duration { return time.Hour }
-func (c *manualCheck) check(_ context.Context) logr.Rec {
+func (c *manualCheck) ID() string             { return c.name }
+func (c *manualCheck) Timeout() time.Duration { return time.Hour }
+func (c *manualCheck) Check(_ context.Context) Rec {
+	if c.opts.heartbeatTTL > 0 {
+		c.beatMu.RLock()
+		beatAt := c.beatAt
+		c.beatMu.RUnlock()
+
+		if ago := time.Since(beatAt); ago > c.opts.heartbeatTTL {
+			// The time of the record is the moment of expiration, so the record is counted once by thresholds.
+			return Rec{
+				Time:  beatAt.Add(c.opts.heartbeatTTL),
+				Error: fmt.Errorf("%w: last heartbeat was %s ago", ErrHeartbeatExpired, ago.Round(time.Millisecond)),
+			}
+		}
+	}
+
 	rec, ok := c.logg.GetLast()
 	if !ok {
 		panic("manual check must have initial state")
 	}
 
-	return rec
+	return newRec(rec)
 }
-func (c *manualCheck) log() []logr.Rec {
-	return c.logg.SlicePrev()
+func (c *manualCheck) Log() []Rec {
+	return just.SliceMap(c.logg.SlicePrev(), newRec)
 }
+func (c *manualCheck) history() *logr.Ring { return c.logg }
 
 type bgCheck struct {
 	name   string
@@ -102,14 +185,49 @@ type bgCheck struct {
 	ttl    time.Duration
 	fn     CheckFn
 	logg   *logr.Ring
+	opts   bgOptions
+
+	scheduleMu *sync.RWMutex
+	interval   time.Duration
+	nextRunAt  time.Time
+
+	triggerCh chan struct{}
+
+	pauseMu  *sync.RWMutex
+	pausedAt time.Time
+	pauseErr *pausedError
+
+	// initialized is true when the initial run was made by Register and the loop has not started yet.
+	initialized *atomic.Bool
 }
 
 // NewBackground will create a check that runs in background. Usually used for slow or expensive checks.
-// Note: period should be greater than timeout.
+// Note: period should be greater than timeout. Use WithSchedule to run the check by cron or at wall-clock times
+// instead of period. Panics when period is not positive and WithSchedule is not used.
 //
 //	hc, _ := healthcheck.New(...)
 //	hc.Register(healthcheck.NewBackground("some_subsystem"))
-func NewBackground(name string, initialErr error, delay, period, timeout time.Duration, fn CheckFn) *bgCheck {
+func NewBackground(name string, initialErr error, delay, period, timeout time.Duration, fn CheckFn, opts ...func(*bgOptions)) *bgCheck {
+	options := bgOptions{
+		jitter:       0,
+		initialRun:   false,
+		backoffMin:   0,
+		backoffMax:   0,
+		pausedStatus: StatusPaused,
+		schedule:     nil,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.schedule == nil {
+		if period <= 0 {
+			panic("healthcheck: period of background check should be positive")
+		}
+
+		options.schedule = Every(period)
+	}
+
 	check := &bgCheck{
 		name:   name,
 		period: period,
@@ -117,6 +235,19 @@ func NewBackground(name string, initialErr error, delay, period, timeout time.Du
 		ttl:    timeout,
 		fn:     fn,
 		logg:   logr.New(),
+		opts:   options,
+
+		scheduleMu: new(sync.RWMutex),
+		interval:   0,
+		nextRunAt:  time.Time{},
+
+		triggerCh: make(chan struct{}, 1),
+
+		pauseMu:  new(sync.RWMutex),
+		pausedAt: time.Time{},
+		pauseErr: nil,
+
+		initialized: new(atomic.Bool),
 	}
 
 	check.logg.Put(logr.Rec{
@@ -127,48 +258,238 @@ func NewBackground(name string, initialErr error, delay, period, timeout time.Du
 	return check
 }
 
-func (c *bgCheck) run(ctx context.Context) {
+// run starts the loop of the check. The loop will be stopped when ctx is done. wg will be released after the loop
+// exits and the running check function returns.
+func (c *bgCheck) run(ctx context.Context, logger ILogger, wg *sync.WaitGroup) {
+	wg.Add(1)
 	go func() {
-		time.Sleep(c.delay)
+		defer wg.Done()
 
-		t := time.NewTicker(c.period)
-		defer t.Stop()
+		firstRunAt := time.Now().Add(c.delay)
 
-		for {
-			func() {
-				ctx, cancel := context.WithTimeout(ctx, c.ttl)
-				defer cancel()
+		_, relative := c.opts.schedule.(everySchedule)
+		if !relative {
+			// Wall-clock schedules start at the first scheduled time after the delay.
+			firstRunAt = c.opts.schedule.Next(firstRunAt)
+		}
 
-				err := c.fn(ctx)
+		c.setSchedule(scheduleInterval(c.opts.schedule, firstRunAt), firstRunAt)
+		if !c.wait(ctx, firstRunAt) {
+			return
+		}
 
-				c.logg.Put(logr.Rec{
-					Time:  time.Now(),
-					Error: err,
-				})
-			}()
+		// The initial run made by Register replaces the first run when it should happen immediately.
+		failures := 0
+		skipRun := c.initialized.Swap(false) && relative && c.delay == 0
+		if rec, ok := c.logg.GetLast(); skipRun && ok && rec.Error != nil {
+			failures = 1
+		}
+
+		for {
+			start := time.Now()
+			if !skipRun {
+				switch err := c.runOnce(ctx, logger); {
+				case errors.Is(err, errSkipped):
+				case err != nil:
+					failures++
+				default:
+					failures = 0
+				}
+			}
+			skipRun = false
 
-			select {
-			case <-ctx.Done():
+			next := c.nextRun(start, failures)
+			c.setSchedule(next.Sub(start), next)
+			if !c.wait(ctx, next) {
 				return
-			case <-t.C:
 			}
 		}
 	}()
 }
 
-func (c *bgCheck) id() string             { return c.name }
-func (c *bgCheck) timeout() time.Duration { return time.Hour }
-func (c *bgCheck) check(_ context.Context) logr.Rec {
+// wait waits for the time or for the trigger. Zero time means to wait only for the trigger. Returns false when ctx is
+// done before that.
+func (c *bgCheck) wait(ctx context.Context, until time.Time) bool {
+	var timerCh <-chan time.Time
+	if !until.IsZero() {
+		t := time.NewTimer(max(time.Until(until), 0))
+		defer t.Stop()
+
+		timerCh = t.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timerCh:
+		return true
+	case <-c.triggerCh:
+		return true
+	}
+}
+
+// Pause stops running the check until Resume is called. While the check is paused it reports StatusPaused (or the
+// status configured by WithPausedStatus) with the given reason.
+func (c *bgCheck) Pause(reason string) {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+
+	c.pausedAt = time.Now()
+	c.pauseErr = &pausedError{
+		reason: reason,
+		status: c.opts.pausedStatus,
+	}
+}
+
+// Resume resumes the paused check. The check will run on its normal schedule.
+func (c *bgCheck) Resume() {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+
+	c.pausedAt = time.Time{}
+	c.pauseErr = nil
+}
+
+func (c *bgCheck) isPaused() bool {
+	c.pauseMu.RLock()
+	defer c.pauseMu.RUnlock()
+
+	return c.pauseErr != nil
+}
+
+// Trigger requests an immediate out-of-schedule run of the check. It is safe to call it concurrently: requests that
+// come while the run is in progress are coalesced into one next run. Runs never overlap.
+func (c *bgCheck) Trigger() {
+	select {
+	case c.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// runInitial makes the initial run of the check. See WithInitialRun.
+func (c *bgCheck) runInitial(ctx context.Context, logger ILogger) {
+	c.runOnce(ctx, logger)
+	c.initialized.Store(true)
+}
+
+// runOnce runs the check function, stores and returns the result.
+func (c *bgCheck) runOnce(ctx context.Context, logger ILogger) error {
+	if c.isPaused() {
+		return errSkipped
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.ttl)
+	defer cancel()
+
+	start := time.Now()
+	details, err := splitDetails(callSafe(ctx, c.fn))
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The check was interrupted by stopping the loop. This is not the state of the check.
+		return ctx.Err()
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		logger.ErrorContext(ctx, "check panicked",
+			slog.String("check", c.name),
+			slog.String("error", panicErr.Error()))
+	}
+
+	c.logg.Put(logr.Rec{
+		Time:     time.Now(),
+		Error:    err,
+		Duration: time.Since(start),
+		Details:  details,
+	})
+
+	return err
+}
+
+// nextRun returns the time of the next run after the run started at start, depending on the number of failures in
+// a row. Zero time means that there are no more runs by schedule.
+func (c *bgCheck) nextRun(start time.Time, failures int) time.Time {
+	next := c.opts.schedule.Next(start)
+	if c.opts.backoffMin > 0 && failures > 0 {
+		period := c.opts.backoffMin
+		for i := 1; i < failures && period < c.opts.backoffMax; i++ {
+			period *= 2
+		}
+
+		// Backoff only lengthens the normal schedule, so the failing check never runs more often than the healthy one.
+		if backoff := start.Add(min(period, c.opts.backoffMax)); !next.IsZero() && backoff.After(next) {
+			next = backoff
+		}
+	}
+
+	if c.opts.jitter > 0 && !next.IsZero() {
+		next = next.Add(rand.N(c.opts.jitter))
+	}
+
+	return next
+}
+
+// scheduleInterval returns the interval between the run at the given time and the next run by schedule. Returns zero
+// when there are no more runs.
+func scheduleInterval(schedule Schedule, runAt time.Time) time.Duration {
+	if runAt.IsZero() {
+		return 0
+	}
+
+	next := schedule.Next(runAt)
+	if next.IsZero() {
+		return 0
+	}
+
+	return max(next.Sub(runAt), 0)
+}
+
+func (c *bgCheck) setSchedule(interval time.Duration, nextRunAt time.Time) {
+	c.scheduleMu.Lock()
+	defer c.scheduleMu.Unlock()
+
+	c.interval = interval
+	c.nextRunAt = nextRunAt
+}
+
+func (c *bgCheck) scheduleInfo() *ScheduleInfo {
+	c.scheduleMu.RLock()
+	defer c.scheduleMu.RUnlock()
+
+	if c.nextRunAt.IsZero() {
+		return nil
+	}
+
+	return &ScheduleInfo{
+		Interval:  c.interval,
+		NextRunAt: c.nextRunAt,
+	}
+}
+
+func (c *bgCheck) ID() string             { return c.name }
+func (c *bgCheck) Timeout() time.Duration { return time.Hour }
+func (c *bgCheck) Check(_ context.Context) Rec {
+	c.pauseMu.RLock()
+	pausedAt, pauseErr := c.pausedAt, c.pauseErr
+	c.pauseMu.RUnlock()
+
+	if pauseErr != nil {
+		return Rec{
+			Time:  pausedAt,
+			Error: pauseErr,
+		}
+	}
+
 	val, ok := c.logg.GetLast()
 	if !ok {
-		return logr.Rec{
+		return Rec{
 			Time:  time.Now(),
 			Error: nil,
 		}
 	}
 
-	return val
+	return newRec(val)
 }
-func (c *bgCheck) log() []logr.Rec {
-	return c.logg.SlicePrev()
+func (c *bgCheck) Log() []Rec {
+	return just.SliceMap(c.logg.SlicePrev(), newRec)
 }
+func (c *bgCheck) history() *logr.Ring { return c.logg }
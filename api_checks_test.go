@@ -16,26 +16,26 @@ func TestManualCheck2(t *testing.T) {
 
 		check := NewManual("sample")
 
-		require.Equal(t, "sample", check.id())
-		require.Equal(t, time.Hour, check.timeout())
-		require.ErrorIs(t, check.check(context.TODO()).Error, errInitial)
-		require.Len(t, check.log(), 0)
+		require.Equal(t, "sample", check.ID())
+		require.Equal(t, time.Hour, check.Timeout())
+		require.ErrorIs(t, check.Check(context.TODO()).Error, errInitial)
+		require.Len(t, check.Log(), 0)
 	})
 
 	t.Run("set_and_unset_error", func(t *testing.T) {
 		t.Parallel()
 
 		check := NewManual("sample")
-		require.Error(t, check.check(context.TODO()).Error)
+		require.Error(t, check.Check(context.TODO()).Error)
 
 		check.SetErr(nil)
-		require.NoError(t, check.check(context.TODO()).Error)
+		require.NoError(t, check.Check(context.TODO()).Error)
 
 		check.SetErr(io.EOF)
-		require.ErrorIs(t, check.check(context.TODO()).Error, io.EOF)
+		require.ErrorIs(t, check.Check(context.TODO()).Error, io.EOF)
 
 		t.Run("check_logs", func(t *testing.T) {
-			records := check.log()
+			records := check.Log()
 			require.Len(t, records, 2)
 
 			require.NoError(t, records[0].Error)
@@ -43,3 +43,37 @@ func TestManualCheck2(t *testing.T) {
 		})
 	})
 }
+
+func TestBackgroundCheckJitter(t *testing.T) {
+	t.Parallel()
+
+	check := NewBackground("sample", nil, 0, time.Second, time.Second, nil, WithJitter(100*time.Millisecond))
+	start := time.Now()
+	for range 100 {
+		period := check.nextRun(start, 0).Sub(start)
+		require.GreaterOrEqual(t, period, time.Second)
+		require.Less(t, period, time.Second+100*time.Millisecond)
+	}
+
+	check = NewBackground("sample", nil, 0, time.Second, time.Second, nil)
+	require.Equal(t, start.Add(time.Second), check.nextRun(start, 0))
+}
+
+func TestBackgroundCheckBackoff(t *testing.T) {
+	t.Parallel()
+
+	check := NewBackground("sample", nil, 0, time.Second, time.Second, nil, WithBackoff(2*time.Second, 10*time.Second))
+	start := time.Now()
+
+	require.Equal(t, start.Add(time.Second), check.nextRun(start, 0))
+	require.Equal(t, start.Add(2*time.Second), check.nextRun(start, 1))
+	require.Equal(t, start.Add(4*time.Second), check.nextRun(start, 2))
+	require.Equal(t, start.Add(8*time.Second), check.nextRun(start, 3))
+	require.Equal(t, start.Add(10*time.Second), check.nextRun(start, 4))
+	require.Equal(t, start.Add(10*time.Second), check.nextRun(start, 100))
+
+	// Backoff should not make the period shorter than the normal one.
+	check = NewBackground("sample", nil, 0, time.Hour, time.Second, nil, WithBackoff(time.Second, 10*time.Second))
+	require.Equal(t, start.Add(time.Hour), check.nextRun(start, 1))
+	require.Equal(t, start.Add(time.Hour), check.nextRun(start, 100))
+}
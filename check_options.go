@@ -0,0 +1,201 @@
+package healthcheck
+
+import (
+	"maps"
+	"time"
+)
+
+type checkOptions struct {
+	critical           bool
+	failureThreshold   int
+	successThreshold   int
+	flapTransitions    int
+	flapWindow         time.Duration
+	flapHold           Status
+	historySize        int
+	historyRetention   time.Duration
+	historyTransitions bool
+	metadata           map[string]string
+	tags               []string
+	dependsOn          []string
+	liveness           bool
+}
+
+func defaultCheckOptions() checkOptions {
+	return checkOptions{
+		critical:         true,
+		failureThreshold: 1,
+		successThreshold: 1,
+	}
+}
+
+// WithNonCritical marks the check as non-critical. When a non-critical check is down, the report will have
+// StatusDegraded instead of StatusDown.
+func WithNonCritical() func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.critical = false
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive failed attempts after which the check will be reported as down.
+// Each attempt still will be available in Check.Previous.
+func WithFailureThreshold(n int) func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.failureThreshold = max(n, 1)
+	}
+}
+
+// WithSuccessThreshold sets the number of consecutive successful attempts after which the failed check will be
+// reported as up again.
+func WithSuccessThreshold(n int) func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.successThreshold = max(n, 1)
+	}
+}
+
+// WithHistory sets the max number of records that will be stored for the check and, optionally, the max age of
+// records. Zero retention means that records are not limited by age. It works for basic, manual and background
+// checks and overrides WithDefaultHistory.
+//
+//	// keep everything from the last 15 minutes, capped at 100 records
+//	hc.Register(ctx, check, healthcheck.WithHistory(100, 15*time.Minute))
+func WithHistory(size int, retention time.Duration) func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.historySize = size
+		o.historyRetention = retention
+	}
+}
+
+// WithTransitionHistory enables the history mode when only state transitions (up to down, down to up and changes
+// of error text) are stored. Repeats of the same state are counted instead of being stored, so real incidents are not
+// pushed out of the history by the stream of identical results.
+func WithTransitionHistory() func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.historyTransitions = true
+	}
+}
+
+// WithFlapDetection marks the check as flapping when it changes the state more than transitions times within the
+// window. While the check is flapping its reported status is held on hold value: StatusDown for pessimistic or
+// StatusUp for optimistic behaviour. Other hold values are rejected by Register with ErrInvalidOption. Detection is
+// based on the check history: it is extended to keep at least transitions+2 records, but make sure that the history
+// is deep enough to contain the window.
+func WithFlapDetection(transitions int, window time.Duration, hold Status) func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.flapTransitions = transitions
+		o.flapWindow = window
+		o.flapHold = hold
+	}
+}
+
+// WithMetadata attaches static information to the check, like the owner team or the runbook URL. It is reported in
+// the metadata of the check.
+func WithMetadata(metadata map[string]string) func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.metadata = maps.Clone(metadata)
+	}
+}
+
+// WithTags adds tags to the check, like `db`, `external` or `cache`. Tags can be used to filter reports, see
+// WithInclude and WithExclude.
+func WithTags(tags ...string) func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithDependsOn declares that the check depends on already registered checks with given names. When any of them is
+// down, the check is not run and reported as StatusSkipped with the name of the failed dependency. Critical checks are
+// never skipped because of non-critical dependencies, so they can not hide the failure of the service.
+func WithDependsOn(names ...string) func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.dependsOn = append(o.dependsOn, names...)
+	}
+}
+
+// WithLiveness registers the check as a liveness check, like a stuck event loop or a wedged goroutine. Liveness checks
+// are not part of the readiness report. They are run by RunAllChecks with WithLivenessChecks and drive the /live
+// handler of the server, so the failed liveness check leads to the restart of the pod.
+func WithLiveness() func(*checkOptions) {
+	return func(o *checkOptions) {
+		o.liveness = true
+	}
+}
+
+type basicOptions struct {
+	cacheTTL time.Duration
+}
+
+// WithCacheTTL allows the basic check to reuse the last result during ttl instead of running the check function on
+// each RunAllChecks. This protects dependencies from a storm of probes.
+func WithCacheTTL(ttl time.Duration) func(*basicOptions) {
+	return func(o *basicOptions) {
+		o.cacheTTL = ttl
+	}
+}
+
+type manualOptions struct {
+	heartbeatTTL time.Duration
+}
+
+// WithHeartbeatTTL turns the manual check into the dead-man's switch. When neither Beat nor SetErr is called during
+// ttl, the check reports ErrHeartbeatExpired with the time passed since the last heartbeat.
+func WithHeartbeatTTL(ttl time.Duration) func(*manualOptions) {
+	return func(o *manualOptions) {
+		o.heartbeatTTL = ttl
+	}
+}
+
+type bgOptions struct {
+	jitter       time.Duration
+	initialRun   bool
+	backoffMin   time.Duration
+	backoffMax   time.Duration
+	pausedStatus Status
+	schedule     Schedule
+}
+
+// WithJitter adds a random delay in [0, jitter) to each period of the background check. This spreads the load on
+// dependencies from many replicas of the service.
+func WithJitter(jitter time.Duration) func(*bgOptions) {
+	return func(o *bgOptions) {
+		o.jitter = jitter
+	}
+}
+
+// WithInitialRun will run the background check synchronously inside Register and Replace, so the initial state of the
+// check is the real one instead of initialErr.
+func WithInitialRun() func(*bgOptions) {
+	return func(o *bgOptions) {
+		o.initialRun = true
+	}
+}
+
+// WithBackoff will increase the period of the background check while it is failing. The first retry happens after
+// minPeriod, and each next one after the doubled period, but not more than maxPeriod. The backoff never makes the
+// period shorter than the normal one. The period goes back to normal once the check succeeds.
+func WithBackoff(minPeriod, maxPeriod time.Duration) func(*bgOptions) {
+	return func(o *bgOptions) {
+		o.backoffMin = minPeriod
+		o.backoffMax = max(minPeriod, maxPeriod)
+	}
+}
+
+// WithPausedStatus sets the status that the background check reports while it is paused. Default is StatusPaused.
+func WithPausedStatus(status Status) func(*bgOptions) {
+	return func(o *bgOptions) {
+		o.pausedStatus = status
+	}
+}
+
+// WithSchedule sets the schedule of the background check instead of the fixed period. Except for Every, the first
+// run happens at the first scheduled time after the delay, so use WithInitialRun to get the real state earlier. The
+// period of the check is ignored in this case.
+//
+//	sched, _ := healthcheck.ParseCron("*/5 * * * *")
+//	healthcheck.NewBackground("billing", nil, 0, 0, time.Minute, checkBilling, healthcheck.WithSchedule(sched))
+func WithSchedule(schedule Schedule) func(*bgOptions) {
+	return func(o *bgOptions) {
+		o.schedule = schedule
+	}
+}
@@ -0,0 +1,20 @@
+package healthcheck
+
+import "errors"
+
+var (
+	// ErrCheckNotFound returned when there is no check with the given id.
+	ErrCheckNotFound = errors.New("check not found")
+	// ErrInvalidName returned by Register in strict mode when the name of the check is not a valid id.
+	ErrInvalidName = errors.New("invalid check name")
+	// ErrDuplicateName returned by Register in strict mode when the check with the same name is already registered.
+	ErrDuplicateName = errors.New("duplicate check name")
+	// ErrNotBackgroundCheck returned when the operation is supported only by background checks.
+	ErrNotBackgroundCheck = errors.New("not a background check")
+	// ErrHasDependents returned by Unregister when other checks depend on the check. See WithDependsOn.
+	ErrHasDependents = errors.New("check has dependents")
+	// ErrInvalidOption returned by Register when options of the check are not valid.
+	ErrInvalidOption = errors.New("invalid check option")
+	// ErrHeartbeatExpired reported by the manual check when there was no heartbeat during its ttl. See WithHeartbeatTTL.
+	ErrHeartbeatExpired = errors.New("heartbeat expired")
+)
@@ -12,12 +12,18 @@ type Healthcheck struct {
 	checksMu       *sync.RWMutex
 	checks         []checkContainer
 	isShuttingDown bool
+	isRunning      bool
+	bgWG           *sync.WaitGroup
+
+	inflightMu *sync.Mutex
+	inflight   map[string]*inflightRun
 }
 
 func New(opts ...func(*hcOptions)) (*Healthcheck, error) {
 	options := hcOptions{
 		logger:         slog.New(slog.NewJSONHandler(os.Stdout, nil)),
 		setCheckStatus: func(string, Status) {},
+		setCheckState:  func(string, CheckState) {},
 	}
 	for _, opt := range opts {
 		opt(&options)
@@ -27,5 +33,11 @@ func New(opts ...func(*hcOptions)) (*Healthcheck, error) {
 		opts:     options,
 		checksMu: new(sync.RWMutex),
 		checks:   nil,
+
+		isRunning: !options.manualStart,
+		bgWG:      new(sync.WaitGroup),
+
+		inflightMu: new(sync.Mutex),
+		inflight:   make(map[string]*inflightRun),
 	}, nil
 }
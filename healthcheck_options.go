@@ -1,8 +1,31 @@
 package healthcheck
 
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type hcOptions struct {
 	logger         ILogger
 	setCheckStatus func(checkID string, isReady Status)
+	setCheckState  func(checkID string, state CheckState)
+	singleflight   bool
+	strictNames    bool
+	manualStart    bool
+	maxConcurrency int
+	reportTimeout  time.Duration
+
+	historySize      int
+	historyRetention time.Duration
+}
+
+// WithHealthcheckLogger sets the logger for healthcheck events like renamed checks or panics inside checks.
+func WithHealthcheckLogger(logger ILogger) func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.logger = logger
+	}
 }
 
 // WithCheckStatusFn will provide a function that will be called at each check changes.
@@ -11,3 +34,128 @@ func WithCheckStatusFn(fn func(checkID string, isReady Status)) func(*hcOptions)
 		o.setCheckStatus = fn
 	}
 }
+
+// WithCheckStateFn will provide a function that will be called with the actual state of the check on each run. Use
+// it to export the duration of checks to metrics.
+func WithCheckStateFn(fn func(checkID string, state CheckState)) func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.setCheckState = fn
+	}
+}
+
+// WithStrictNames will force Register to return an error for invalid or duplicated names instead of renaming checks.
+// Valid name contains only lowercase letters, digits and lodash.
+func WithStrictNames() func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.strictNames = true
+	}
+}
+
+// WithManualStart will not start background checks in Register. Call Healthcheck.Start to start them.
+func WithManualStart() func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.manualStart = true
+	}
+}
+
+// WithSingleflight will coalesce concurrent RunAllChecks calls. Callers that come while a run is in progress will
+// wait for it and receive the same report instead of running all checks again.
+func WithSingleflight() func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.singleflight = true
+	}
+}
+
+// WithMaxConcurrency limits the number of checks that RunAllChecks runs in parallel. Zero means no limit. The check
+// that ignores ctx occupies its slot until it returns, even after its timeout.
+func WithMaxConcurrency(n int) func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithReportTimeout sets the deadline for the whole RunAllChecks call. Checks that are not finished by the deadline
+// will be reported as down with a deadline error. Keep it less than the timeout of k8s probe.
+func WithReportTimeout(timeout time.Duration) func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.reportTimeout = timeout
+	}
+}
+
+// WithDefaultHistory sets the max number of records and the max age of records that will be stored for each check.
+// See WithHistory.
+func WithDefaultHistory(size int, retention time.Duration) func(*hcOptions) {
+	return func(o *hcOptions) {
+		o.historySize = size
+		o.historyRetention = retention
+	}
+}
+
+// RunOption is an option of Healthcheck.RunAllChecks. It is a named type to allow implementing IHealthcheck outside
+// the package.
+type RunOption func(*runOptions)
+
+type runOptions struct {
+	include  []string
+	exclude  []string
+	liveness bool
+}
+
+// key returns the key of the filter. Runs with the same key are shared, see WithSingleflight.
+func (o runOptions) key() string {
+	return strconv.FormatBool(o.liveness) + "|" + strings.Join(o.include, ",") + "|" + strings.Join(o.exclude, ",")
+}
+
+// strictInclude returns true when the run should fail if WithInclude matches no checks. An empty result is expected
+// for exclude-only filters and for liveness checks, which should not restart the pod because of the filter.
+func (o runOptions) strictInclude() bool {
+	return len(o.include) != 0 && !o.liveness
+}
+
+// match returns true when the check should be included into the report.
+func (o runOptions) match(check checkContainer) bool {
+	matches := func(names []string) bool {
+		return slices.ContainsFunc(names, func(name string) bool {
+			return name == check.ID || slices.Contains(check.Opts.tags, name)
+		})
+	}
+
+	if check.Opts.liveness != o.liveness {
+		return false
+	}
+
+	if len(o.include) != 0 && !matches(o.include) {
+		return false
+	}
+
+	return !matches(o.exclude)
+}
+
+// WithInclude limits the report to checks with given names or tags. When it matches no checks, the readiness report
+// is down, because this is most likely a typo in the probe.
+//
+//	hc.RunAllChecks(ctx, healthcheck.WithInclude("db"))
+func WithInclude(namesOrTags ...string) RunOption {
+	return func(o *runOptions) {
+		o.include = append(o.include, namesOrTags...)
+	}
+}
+
+// WithExclude removes checks with given names or tags from the report. Excluded checks are not running, unless other
+// checks of the report depend on them.
+//
+//	hc.RunAllChecks(ctx, healthcheck.WithExclude("external"))
+func WithExclude(namesOrTags ...string) RunOption {
+	return func(o *runOptions) {
+		o.exclude = append(o.exclude, namesOrTags...)
+	}
+}
+
+// WithLivenessChecks runs liveness checks instead of readiness checks. See WithLiveness.
+//
+//	hc.RunAllChecks(ctx, healthcheck.WithLivenessChecks())
+func WithLivenessChecks() RunOption {
+	return func(o *runOptions) {
+		o.liveness = true
+	}
+}
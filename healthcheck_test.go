@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -165,8 +167,9 @@ func TestBackgroundCheck(t *testing.T) {
 		}, res)
 	})
 
-	// wait for bg check next run
-	time.Sleep(delay)
+	// wait for bg check next run. The check runs exactly at delay and then each period, so waking up at the same
+	// moments races with the run. Shift all next waits by the half of delay.
+	time.Sleep(delay + delay/2)
 
 	t.Run("check_current_error_nil", func(t *testing.T) {
 		res := hcInst.RunAllChecks(context.Background())
@@ -210,6 +213,47 @@ func TestBackgroundCheck(t *testing.T) {
 
 }
 
+type customCheck struct {
+	err error
+}
+
+func (c *customCheck) ID() string             { return "custom" }
+func (c *customCheck) Timeout() time.Duration { return time.Second }
+func (c *customCheck) Check(context.Context) hc.Rec {
+	return hc.Rec{Time: time.Now(), Error: c.err}
+}
+func (c *customCheck) Log() []hc.Rec {
+	return []hc.Rec{{Time: time.Now(), Error: io.EOF}}
+}
+
+func TestCustomCheck(t *testing.T) {
+	t.Parallel()
+
+	res := hcWithChecks(t, &customCheck{err: nil}).RunAllChecks(context.Background())
+	requireReportEqual(t, hc.Report{
+		Status: hc.StatusUp,
+		Checks: []hc.Check{
+			{
+				Name:  "custom",
+				State: hc.CheckState{ActualAt: timeNow, Status: hc.StatusUp, Error: ""},
+				Previous: []hc.CheckState{
+					{ActualAt: timeNow, Status: hc.StatusDown, Error: "EOF"},
+				},
+			},
+		},
+	}, res)
+	details := map[string]any{"queue_depth": 3}
+	res = hcWithChecks(t, &customCheck{err: hc.Detailed(nil, details)}).RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusUp, "details without error should be up, got %s", res.Status)
+	requireTrue(t, res.Checks[0].State.Details["queue_depth"] == 3, "details of custom check should be reported")
+
+	res = hcWithChecks(t, &customCheck{err: fmt.Errorf("queue: %w", hc.Detailed(nil, details))}).RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "wrapped details without error should be down, got %s", res.Status)
+	requireTrue(t, res.Checks[0].State.Error == "queue: details without error", "text of wrapping error should be kept, got %q",
+		res.Checks[0].State.Error)
+	requireTrue(t, res.Checks[0].State.Details["queue_depth"] == 3, "details of wrapped result should be reported")
+}
+
 func TestService(t *testing.T) { //nolint:funlen
 	t.Run("empty_healthcheck_have_status_up", func(t *testing.T) {
 		t.Parallel()
@@ -274,6 +318,26 @@ func TestService(t *testing.T) { //nolint:funlen
 		}, res)
 	})
 
+	t.Run("strict_names", func(t *testing.T) {
+		t.Parallel()
+
+		hcInst, err := hc.New(hc.WithStrictNames())
+		requireNoError(t, err)
+
+		requireNoError(t, hcInst.Register(context.TODO(), simpleCheck("db", nil)))
+
+		err = hcInst.Register(context.TODO(), simpleCheck("db", nil))
+		requireTrue(t, errors.Is(err, hc.ErrDuplicateName), "unexpected error: %v", err)
+
+		for _, name := range []string{"DB", "db-replica", "db.replica", ""} {
+			err = hcInst.Register(context.TODO(), simpleCheck(name, nil))
+			requireTrue(t, errors.Is(err, hc.ErrInvalidName), "unexpected error for %q: %v", name, err)
+		}
+
+		res := hcInst.RunAllChecks(context.Background())
+		requireTrue(t, len(res.Checks) == 1, "rejected checks should not be registered")
+	})
+
 	t.Run("fail_when_at_least_one_check_failed", func(t *testing.T) {
 		t.Parallel()
 
@@ -302,6 +366,339 @@ func TestService(t *testing.T) { //nolint:funlen
 	})
 }
 
+func TestCriticality(t *testing.T) {
+	t.Parallel()
+
+	t.Run("non_critical_failure_degrades_report", func(t *testing.T) {
+		t.Parallel()
+
+		hcInst := hcWithChecks(t, simpleCheck("db", nil))
+		hcInst.Register(context.TODO(), simpleCheck("cache", io.EOF), hc.WithNonCritical())
+
+		res := hcInst.RunAllChecks(context.Background())
+		requireReportEqual(t, hc.Report{
+			Status: hc.StatusDegraded,
+			Checks: []hc.Check{
+				{Name: "db", State: hc.CheckState{ActualAt: timeNow, Status: hc.StatusUp, Error: ""}},
+				{Name: "cache", State: hc.CheckState{ActualAt: timeNow, Status: hc.StatusDown, Error: "EOF"}},
+			},
+		}, res)
+		requireTrue(t, res.Checks[0].Critical, "checks are critical by default")
+		requireTrue(t, !res.Checks[1].Critical, "check should be marked as non-critical")
+	})
+
+	t.Run("critical_failure_wins", func(t *testing.T) {
+		t.Parallel()
+
+		hcInst := hcWithChecks(t, simpleCheck("db", io.EOF))
+		hcInst.Register(context.TODO(), simpleCheck("cache", io.EOF), hc.WithNonCritical())
+
+		res := hcInst.RunAllChecks(context.Background())
+		requireTrue(t, res.Status == hc.StatusDown, "critical check is down")
+	})
+}
+
+func TestThresholds(t *testing.T) {
+	t.Parallel()
+
+	var curErr error
+	check := hc.NewBasic("db", time.Second, func(ctx context.Context) error { return curErr })
+
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+	hcInst.Register(context.TODO(), check, hc.WithFailureThreshold(2), hc.WithSuccessThreshold(2))
+
+	run := func(err error) hc.Check {
+		curErr = err
+		return hcInst.RunAllChecks(context.Background()).Checks[0] //nolint:nlreturn
+	}
+
+	requireTrue(t, run(nil).State.Status == hc.StatusUp, "first success")
+
+	res := run(io.EOF)
+	requireTrue(t, res.State.Status == hc.StatusUp, "one failure is below threshold")
+	requireTrue(t, res.State.Error == "EOF", "raw error should be reported")
+	requireTrue(t, len(res.Previous) == 1 && res.Previous[0].Status == hc.StatusUp, "raw attempts are kept")
+
+	requireTrue(t, run(io.EOF).State.Status == hc.StatusDown, "two failures reach threshold")
+	requireTrue(t, run(nil).State.Status == hc.StatusDown, "one success is below threshold")
+	requireTrue(t, run(nil).State.Status == hc.StatusUp, "two successes reach threshold")
+}
+
+func TestThresholdsBackground(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewBackground("bg", nil, 20*time.Millisecond, 5*time.Millisecond, time.Second, func(context.Context) error {
+		return io.EOF
+	})
+
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+	requireNoError(t, hcInst.Register(context.Background(), check, hc.WithFailureThreshold(3)))
+	defer hcInst.Close(context.Background())
+
+	requireTrue(t, hcInst.RunAllChecks(context.Background()).Status == hc.StatusUp, "initial state is up")
+
+	// The check fails many times between two reports. All attempts should be counted, not only reports.
+	time.Sleep(100 * time.Millisecond)
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "failures between reports should reach threshold")
+	requireTrue(t, res.Checks[0].Stats.ConsecutiveFailures > 5,
+		"all failed attempts should be counted, not only stored ones, got %d", res.Checks[0].Stats.ConsecutiveFailures)
+}
+
+func TestFlapDetection(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewManual("queue")
+
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+	hcInst.Register(context.TODO(), check, hc.WithFlapDetection(2, time.Minute, hc.StatusUp))
+
+	check.SetErr(nil)
+	check.SetErr(io.EOF)
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, !res.Checks[0].Flapping, "two transitions is not flapping")
+	requireTrue(t, res.Status == hc.StatusDown, "not flapping check reports actual status")
+
+	check.SetErr(nil)
+	check.SetErr(io.EOF)
+
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Checks[0].Flapping, "four transitions is flapping")
+	requireTrue(t, res.Checks[0].State.Status == hc.StatusUp, "flapping check should hold configured status")
+	requireTrue(t, res.Checks[0].State.Error == "EOF", "flapping check should keep an error")
+	requireTrue(t, res.Status == hc.StatusUp, "report should use held status")
+}
+
+func TestFlapDetectionHistory(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewManual("queue")
+
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+	requireNoError(t, hcInst.Register(context.TODO(), check, hc.WithFlapDetection(6, time.Minute, hc.StatusDown)))
+
+	// Seven transitions need eight records, which is more than the default history.
+	check.SetErr(io.EOF)
+	for range 4 {
+		check.SetErr(nil)
+		check.SetErr(io.EOF)
+	}
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Checks[0].Flapping, "history should be deep enough to detect flapping")
+	// Previous does not contain the current state.
+	requireTrue(t, len(res.Checks[0].Previous)+1 >= 8, "history should keep transitions+2 records, got %d",
+		len(res.Checks[0].Previous)+1)
+}
+
+func TestFlapDetectionInvalidHold(t *testing.T) {
+	t.Parallel()
+
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+
+	err = hcInst.Register(context.TODO(), hc.NewManual("queue"), hc.WithFlapDetection(2, time.Minute, hc.StatusDegraded))
+	requireTrue(t, errors.Is(err, hc.ErrInvalidOption), "only up and down can be held, got %v", err)
+	requireTrue(t, len(hcInst.RunAllChecks(context.Background()).Checks) == 0, "check should not be registered")
+}
+
+func TestBasicCheckCache(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	check := hc.NewBasic("db", time.Second, func(ctx context.Context) error {
+		calls++
+		return nil //nolint:nlreturn
+	}, hc.WithCacheTTL(100*time.Millisecond))
+	hcInst := hcWithChecks(t, check)
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, !res.Checks[0].State.Cached, "first run should not be cached")
+
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Checks[0].State.Cached, "second run should be cached")
+	requireTrue(t, res.Checks[0].State.Age > 0, "cached state should have an age")
+	requireTrue(t, calls == 1, "check function should be called once, got %d", calls)
+
+	time.Sleep(100 * time.Millisecond)
+
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, !res.Checks[0].State.Cached, "cache should expire")
+	requireTrue(t, calls == 2, "check function should be called again, got %d", calls)
+}
+
+func TestSingleflight(t *testing.T) {
+	t.Parallel()
+
+	callsMu := new(sync.Mutex)
+	calls := 0
+	check := hc.NewBasic("db", time.Second, func(ctx context.Context) error {
+		callsMu.Lock()
+		calls++
+		callsMu.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+
+		return nil
+	})
+
+	hcInst, err := hc.New(hc.WithSingleflight())
+	requireNoError(t, err)
+	hcInst.Register(context.TODO(), check)
+
+	reports := make([]hc.Report, 10)
+	wg := new(sync.WaitGroup)
+	for i := range reports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reports[i] = hcInst.RunAllChecks(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	callsMu.Lock()
+	defer callsMu.Unlock()
+
+	requireTrue(t, calls == 1, "concurrent runs should be coalesced, got %d calls", calls)
+	for i := range reports {
+		requireTrue(t, reports[i].Status == hc.StatusUp, "all callers should receive the report")
+		requireTrue(t, reports[i].Checks[0].State.ActualAt.Equal(reports[0].Checks[0].State.ActualAt), "report should be shared")
+	}
+}
+
+func TestSingleflightCancel(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	check := hc.NewBasic("db", time.Second, func(context.Context) error {
+		<-release
+
+		return nil
+	})
+
+	hcInst, err := hc.New(hc.WithSingleflight())
+	requireNoError(t, err)
+	requireNoError(t, hcInst.Register(context.Background(), check))
+
+	leaderDone := make(chan hc.Report)
+	go func() { leaderDone <- hcInst.RunAllChecks(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+
+	// The follower should not wait for the slow leader after its ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	res := hcInst.RunAllChecks(ctx)
+	requireTrue(t, res.Status == hc.StatusDown, "cancelled follower should receive down, got %s", res.Status)
+
+	close(release)
+	res = <-leaderDone
+	requireTrue(t, res.Status == hc.StatusUp, "leader should receive its report, got %s", res.Status)
+
+	// The first caller should not wait for the shared run after its ctx is done too.
+	release = make(chan struct{})
+	defer close(release)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res = hcInst.RunAllChecks(ctx)
+	requireTrue(t, res.Status == hc.StatusDown, "cancelled leader should receive down, got %s", res.Status)
+	requireTrue(t, time.Since(start) < 500*time.Millisecond, "cancelled leader should return on its deadline")
+}
+
+func TestConcurrencyAndReportTimeout(t *testing.T) {
+	t.Parallel()
+
+	mu := new(sync.Mutex)
+	running, maxRunning := 0, 0
+	slowCheck := func(name string) hc.ICheck {
+		return hc.NewBasic(name, time.Second, func(ctx context.Context) error {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+				return nil
+			}
+		})
+	}
+
+	hcInst, err := hc.New(hc.WithMaxConcurrency(1), hc.WithReportTimeout(150*time.Millisecond))
+	requireNoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		hcInst.Register(context.TODO(), slowCheck(name))
+	}
+
+	start := time.Now()
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, time.Since(start) < 300*time.Millisecond, "report should respect the deadline")
+	requireTrue(t, res.Status == hc.StatusDown, "not finished checks should fail the report")
+
+	timedOut := 0
+	for i := range res.Checks {
+		if res.Checks[i].State.Error == context.DeadlineExceeded.Error() {
+			timedOut++
+		}
+	}
+	requireTrue(t, timedOut == 2, "two checks should be timed out, got %d", timedOut)
+
+	mu.Lock()
+	defer mu.Unlock()
+	requireTrue(t, maxRunning == 1, "checks should not run in parallel, got %d", maxRunning)
+}
+
+func TestConcurrencyIgnoredContext(t *testing.T) {
+	t.Parallel()
+
+	mu := new(sync.Mutex)
+	running, maxRunning := 0, 0
+	stuckCheck := func(name string) hc.ICheck {
+		return hc.NewBasic(name, 20*time.Millisecond, func(context.Context) error {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	hcInst, err := hc.New(hc.WithMaxConcurrency(1))
+	requireNoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		hcInst.Register(context.TODO(), stuckCheck(name))
+	}
+
+	hcInst.RunAllChecks(context.Background())
+	time.Sleep(60 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	requireTrue(t, maxRunning == 1, "checks that ignore ctx should not run in parallel, got %d", maxRunning)
+}
+
 func TestPrevious(t *testing.T) { //nolint:funlen
 	t.Parallel()
 
@@ -335,6 +732,124 @@ func TestPrevious(t *testing.T) { //nolint:funlen
 	})
 }
 
+func TestHistoryLimits(t *testing.T) {
+	t.Parallel()
+
+	t.Run("size_per_check", func(t *testing.T) {
+		t.Parallel()
+
+		check := hc.NewManual("x")
+		hcInst, err := hc.New(hc.WithDefaultHistory(3, 0))
+		requireNoError(t, err)
+		hcInst.Register(context.TODO(), check, hc.WithHistory(10, 0))
+
+		for range 20 {
+			check.SetErr(nil)
+		}
+
+		report := hcInst.RunAllChecks(context.Background())
+		requireTrue(t, len(report.Checks[0].Previous) == 9, "per-check history should be used, got %d", len(report.Checks[0].Previous))
+	})
+
+	t.Run("default_size", func(t *testing.T) {
+		t.Parallel()
+
+		check := hc.NewManual("x")
+		hcInst, err := hc.New(hc.WithDefaultHistory(3, 0))
+		requireNoError(t, err)
+		hcInst.Register(context.TODO(), check)
+
+		for range 20 {
+			check.SetErr(nil)
+		}
+
+		report := hcInst.RunAllChecks(context.Background())
+		requireTrue(t, len(report.Checks[0].Previous) == 2, "default history should be used, got %d", len(report.Checks[0].Previous))
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		t.Parallel()
+
+		check := hc.NewManual("x")
+		hcInst, err := hc.New()
+		requireNoError(t, err)
+		hcInst.Register(context.TODO(), check, hc.WithHistory(100, 50*time.Millisecond))
+
+		check.SetErr(nil)
+		check.SetErr(io.EOF)
+
+		report := hcInst.RunAllChecks(context.Background())
+		requireTrue(t, len(report.Checks[0].Previous) == 2, "fresh records should be kept")
+
+		time.Sleep(60 * time.Millisecond)
+
+		report = hcInst.RunAllChecks(context.Background())
+		requireTrue(t, len(report.Checks[0].Previous) == 0, "old records should be dropped")
+		requireStateEqual(t, hc.CheckState{ActualAt: timeNow, Status: hc.StatusDown, Error: "EOF"}, report.Checks[0].State)
+	})
+}
+
+func TestTransitionHistory(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewManual("x")
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+	hcInst.Register(context.TODO(), check, hc.WithTransitionHistory())
+
+	for range 3 {
+		check.SetErr(nil)
+	}
+	for range 2 {
+		check.SetErr(io.EOF)
+	}
+	check.SetErr(io.ErrUnexpectedEOF)
+	check.SetErr(io.ErrUnexpectedEOF)
+
+	report := hcInst.RunAllChecks(context.Background())
+	current := report.Checks[0].State
+	requireStateEqual(t, hc.CheckState{ActualAt: timeNow, Status: hc.StatusDown, Error: "unexpected EOF"}, current)
+	requireTrue(t, current.Count == 2, "repeats should be counted, got %d", current.Count)
+	requireTrue(t, current.FirstSeen != nil && current.FirstSeen.Before(current.ActualAt), "first seen should be set")
+
+	prev := report.Checks[0].Previous
+	requireTrue(t, len(prev) == 3, "only transitions should be stored, got %d", len(prev))
+	requireStateEqual(t, hc.CheckState{ActualAt: timeNow, Status: hc.StatusDown, Error: "EOF"}, prev[0])
+	requireTrue(t, prev[0].Count == 2, "eof repeated twice")
+	requireStateEqual(t, hc.CheckState{ActualAt: timeNow, Status: hc.StatusUp, Error: ""}, prev[1])
+	requireTrue(t, prev[1].Count == 3, "success repeated 3 times")
+	requireStateEqual(t, hc.CheckState{ActualAt: timeNow, Status: hc.StatusDown, Error: "initial"}, prev[2])
+	requireTrue(t, prev[2].Count == 0 && prev[2].FirstSeen == nil, "single record has no repeats")
+}
+
+func TestCheckStats(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewManual("x")
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+	hcInst.Register(context.TODO(), check, hc.WithHistory(10, 0))
+
+	for range 3 {
+		check.SetErr(nil)
+	}
+
+	report := hcInst.RunAllChecks(context.Background())
+	stats := report.Checks[0].Stats
+	requireTrue(t, stats.ConsecutiveFailures == 0, "no failures in a row")
+
+	check.SetErr(io.EOF)
+	check.SetErr(io.EOF)
+	time.Sleep(10 * time.Millisecond)
+
+	report = hcInst.RunAllChecks(context.Background())
+	stats = report.Checks[0].Stats
+	requireTrue(t, stats.SuccessRatio1m != nil && *stats.SuccessRatio1m == 0.5, "3 of 6 records are successful")
+	requireTrue(t, stats.SuccessRatio1h != nil && *stats.SuccessRatio1h == 0.5, "3 of 6 records are successful")
+	requireTrue(t, stats.ConsecutiveFailures == 2, "two failures in a row, got %d", stats.ConsecutiveFailures)
+	requireTrue(t, stats.SinceChange >= 10*time.Millisecond, "status changed before sleep, got %s", stats.SinceChange)
+}
+
 func TestServiceMetrics(t *testing.T) { //nolint:paralleltest
 	res := make(map[string]hc.Status)
 	mu := new(sync.Mutex)
@@ -357,6 +872,77 @@ func TestServiceMetrics(t *testing.T) { //nolint:paralleltest
 	requireTrue(t, res["check_with_error"] == hc.StatusDown, "response without error must have status UP")
 }
 
+func TestCheckDuration(t *testing.T) { //nolint:paralleltest
+	states := make(map[string]hc.CheckState)
+	mu := new(sync.Mutex)
+	setState := func(id string, state hc.CheckState) {
+		mu.Lock()
+		states[id] = state
+		mu.Unlock()
+	}
+
+	hcInst, err := hc.New(hc.WithCheckStateFn(setState))
+	requireNoError(t, err)
+
+	hcInst.Register(context.TODO(), hc.NewBasic("slow", time.Second, func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil //nolint:nlreturn
+	}))
+
+	_ = hcInst.RunAllChecks(context.Background())
+	res := hcInst.RunAllChecks(context.Background())
+
+	check := res.Checks[0]
+	requireTrue(t, check.State.Duration >= 20*time.Millisecond, "duration should be measured, got %s", check.State.Duration)
+	requireTrue(t, check.Previous[0].Duration >= 20*time.Millisecond, "history should contain duration")
+
+	mu.Lock()
+	defer mu.Unlock()
+	requireTrue(t, states["slow"].Duration == check.State.Duration, "state hook should receive duration")
+}
+
+type testLogger struct {
+	mu     *sync.Mutex
+	errors []string
+}
+
+func (l *testLogger) WarnContext(context.Context, string, ...any) {}
+func (l *testLogger) ErrorContext(_ context.Context, msg string, _ ...any) {
+	l.mu.Lock()
+	l.errors = append(l.errors, msg)
+	l.mu.Unlock()
+}
+
+func (l *testLogger) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.errors)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	logger := &testLogger{mu: new(sync.Mutex), errors: nil}
+	hcInst, err := hc.New(hc.WithHealthcheckLogger(logger))
+	requireNoError(t, err)
+
+	panicFn := func(context.Context) error { panic("boom") }
+	hcInst.Register(context.TODO(), hc.NewBasic("basic", time.Second, panicFn))
+	hcInst.Register(context.TODO(), hc.NewBackground("bg", nil, 0, time.Hour, time.Second, panicFn))
+
+	time.Sleep(50 * time.Millisecond)
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "panicked checks should be down")
+	for i := range res.Checks {
+		requireTrue(t, strings.HasPrefix(res.Checks[i].State.Error, "panic: boom\n"), "unexpected error: %s", res.Checks[i].State.Error)
+		requireTrue(t, strings.Contains(res.Checks[i].State.Error, "healthcheck_test.go"), "error should contain stack excerpt")
+	}
+
+	requireTrue(t, logger.count() == 2, "each panic should be logged once, got %d", logger.count())
+}
+
 func TestBackgroundCheckStop(t *testing.T) {
 	var mu sync.Mutex
 	callCount := 0
@@ -393,6 +979,492 @@ func TestBackgroundCheckStop(t *testing.T) {
 	requireTrue(t, finalCount >= 3, "expected at least 3 calls before stop")
 }
 
+func TestUnregisterAndReplace(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unregister_stops_background_check", func(t *testing.T) {
+		t.Parallel()
+
+		mu := new(sync.Mutex)
+		calls := 0
+		check := hc.NewBackground("bg", nil, 0, 20*time.Millisecond, time.Second, func(ctx context.Context) error {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			return nil //nolint:nlreturn
+		})
+		hcInst := hcWithChecks(t, check, simpleCheck("db", nil))
+
+		time.Sleep(50 * time.Millisecond)
+		requireNoError(t, hcInst.Unregister("bg"))
+		time.Sleep(10 * time.Millisecond) // let the in-flight iteration finish
+
+		mu.Lock()
+		callsAfterStop := calls
+		mu.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requireTrue(t, calls == callsAfterStop, "background check should be stopped")
+
+		res := hcInst.RunAllChecks(context.Background())
+		requireTrue(t, len(res.Checks) == 1 && res.Checks[0].Name == "db", "check should be removed from report")
+	})
+
+	t.Run("unregister_unknown_check", func(t *testing.T) {
+		t.Parallel()
+
+		err := hcWithChecks(t).Unregister("unknown")
+		requireTrue(t, errors.Is(err, hc.ErrCheckNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		t.Parallel()
+
+		hcInst, err := hc.New()
+		requireNoError(t, err)
+		hcInst.Register(context.TODO(), simpleCheck("db", io.EOF), hc.WithNonCritical())
+
+		requireNoError(t, hcInst.Replace(context.TODO(), "db", simpleCheck("other_name", nil)))
+
+		res := hcInst.RunAllChecks(context.Background())
+		requireReportEqual(t, hc.Report{
+			Status: hc.StatusUp,
+			Checks: []hc.Check{
+				{Name: "db", State: hc.CheckState{ActualAt: timeNow, Status: hc.StatusUp, Error: ""}},
+			},
+		}, res)
+		requireTrue(t, !res.Checks[0].Critical, "options should be kept")
+
+		err = hcInst.Replace(context.TODO(), "unknown", simpleCheck("db", nil))
+		requireTrue(t, errors.Is(err, hc.ErrCheckNotFound), "unexpected error: %v", err)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	t.Parallel()
+
+	t.Run("close_waits_for_running_checks", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{})
+		finishedMu := new(sync.Mutex)
+		finished := false
+		check := hc.NewBackground("bg", nil, 0, time.Hour, time.Second, func(ctx context.Context) error {
+			close(started)
+			time.Sleep(50 * time.Millisecond) // ignores ctx
+
+			finishedMu.Lock()
+			finished = true
+			finishedMu.Unlock()
+
+			return nil
+		})
+		hcInst := hcWithChecks(t, check)
+		<-started
+
+		requireNoError(t, hcInst.Close(context.Background()))
+
+		finishedMu.Lock()
+		defer finishedMu.Unlock()
+		requireTrue(t, finished, "close should wait for the check function")
+	})
+
+	t.Run("close_respects_context", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{})
+		check := hc.NewBackground("bg", nil, 0, time.Hour, time.Second, func(ctx context.Context) error {
+			close(started)
+			time.Sleep(200 * time.Millisecond) // ignores ctx
+
+			return nil
+		})
+		hcInst := hcWithChecks(t, check)
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		err := hcInst.Close(ctx)
+		requireTrue(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	})
+
+	t.Run("manual_start", func(t *testing.T) {
+		t.Parallel()
+
+		calls := make(chan struct{}, 10)
+		check := hc.NewBackground("bg", nil, 0, time.Hour, time.Second, func(ctx context.Context) error {
+			calls <- struct{}{}
+			return nil //nolint:nlreturn
+		})
+
+		hcInst, err := hc.New(hc.WithManualStart())
+		requireNoError(t, err)
+		requireNoError(t, hcInst.Register(context.TODO(), check))
+
+		time.Sleep(20 * time.Millisecond)
+		requireTrue(t, len(calls) == 0, "check should not be started before Start")
+
+		hcInst.Start(context.TODO())
+		<-calls
+
+		requireNoError(t, hcInst.Close(context.Background()))
+
+		hcInst.Start(context.TODO())
+		<-calls
+
+		requireNoError(t, hcInst.Close(context.Background()))
+	})
+}
+
+func TestBackgroundInitialRun(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewBackground(
+		"bg",
+		errors.New("not ready"),
+		time.Hour,
+		time.Hour,
+		time.Second,
+		func(ctx context.Context) error { return io.EOF },
+		hc.WithInitialRun(),
+	)
+	hcInst := hcWithChecks(t, check)
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireReportEqual(t, hc.Report{
+		Status: hc.StatusDown,
+		Checks: []hc.Check{
+			{
+				Name:  "bg",
+				State: hc.CheckState{ActualAt: timeNow, Status: hc.StatusDown, Error: "EOF"},
+				Previous: []hc.CheckState{
+					{ActualAt: timeNow, Status: hc.StatusDown, Error: "not ready"},
+				},
+			},
+		},
+	}, res)
+}
+
+func TestBackgroundInitialRunOnce(t *testing.T) {
+	t.Parallel()
+
+	calls := new(atomic.Int32)
+	newCheck := func(name string) hc.ICheck {
+		return hc.NewBackground(name, nil, 0, time.Hour, time.Second, func(ctx context.Context) error {
+			calls.Add(1)
+
+			return nil
+		}, hc.WithInitialRun())
+	}
+
+	hcInst, err := hc.New(hc.WithStrictNames())
+	requireNoError(t, err)
+
+	err = hcInst.Register(context.Background(), newCheck("Bad Name"))
+	requireTrue(t, errors.Is(err, hc.ErrInvalidName), "unexpected error: %v", err)
+	requireTrue(t, calls.Load() == 0, "rejected check should not be run")
+
+	requireNoError(t, hcInst.Register(context.Background(), newCheck("bg")))
+	time.Sleep(50 * time.Millisecond)
+	requireTrue(t, calls.Load() == 1, "initial run should replace the first run, got %d calls", calls.Load())
+
+	requireNoError(t, hcInst.Close(context.Background()))
+}
+
+func TestBackgroundInitialRunReplace(t *testing.T) {
+	t.Parallel()
+
+	calls := new(atomic.Int32)
+	newCheck := func() hc.ICheck {
+		return hc.NewBackground("bg", errors.New("not ready"), time.Hour, time.Hour, time.Second,
+			func(ctx context.Context) error {
+				calls.Add(1)
+
+				return io.EOF
+			}, hc.WithInitialRun())
+	}
+
+	hcInst := hcWithChecks(t, simpleCheck("bg", nil))
+
+	err := hcInst.Replace(context.Background(), "unknown", newCheck())
+	requireTrue(t, errors.Is(err, hc.ErrCheckNotFound), "unexpected error: %v", err)
+	requireTrue(t, calls.Load() == 0, "rejected check should not be run")
+
+	requireNoError(t, hcInst.Replace(context.Background(), "bg", newCheck()))
+	requireTrue(t, calls.Load() == 1, "replaced check should be run before Replace returns, got %d calls", calls.Load())
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Checks[0].State.Error == "EOF", "report should contain the initial run, got %q",
+		res.Checks[0].State.Error)
+}
+
+func TestBackgroundBackoffSchedule(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewBackground(
+		"bg",
+		nil,
+		0,
+		time.Minute,
+		time.Second,
+		func(ctx context.Context) error { return io.EOF },
+		hc.WithBackoff(time.Hour, 10*time.Hour),
+	)
+	hcInst := hcWithChecks(t, check)
+
+	time.Sleep(20 * time.Millisecond)
+
+	res := hcInst.RunAllChecks(context.Background())
+	schedule := res.Checks[0].Schedule
+	requireTrue(t, schedule != nil, "background check should have a schedule")
+	requireTrue(t, schedule.Interval == time.Hour, "failed check should use backoff, got %s", schedule.Interval)
+	requireTrue(t, time.Until(schedule.NextRunAt) > 59*time.Minute, "next run should be scheduled by backoff")
+}
+
+func TestBackgroundTrigger(t *testing.T) {
+	t.Parallel()
+
+	mu := new(sync.Mutex)
+	calls, running, maxRunning := 0, 0, 0
+	check := hc.NewBackground("bg", nil, 0, time.Hour, time.Second, func(ctx context.Context) error {
+		mu.Lock()
+		calls++
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		return nil
+	})
+	hcInst := hcWithChecks(t, check, simpleCheck("db", nil))
+
+	time.Sleep(50 * time.Millisecond)
+
+	wg := new(sync.WaitGroup)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requireNoError(t, hcInst.Trigger("bg"))
+		}()
+	}
+	wg.Wait()
+
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	requireTrue(t, calls >= 2 && calls <= 3, "triggers should be coalesced, got %d calls", calls)
+	requireTrue(t, maxRunning == 1, "runs should not overlap")
+	mu.Unlock()
+
+	err := hcInst.Trigger("db")
+	requireTrue(t, errors.Is(err, hc.ErrNotBackgroundCheck), "unexpected error: %v", err)
+
+	err = hcInst.Trigger("unknown")
+	requireTrue(t, errors.Is(err, hc.ErrCheckNotFound), "unexpected error: %v", err)
+}
+
+func TestBackgroundPause(t *testing.T) {
+	t.Parallel()
+
+	mu := new(sync.Mutex)
+	calls := 0
+	check := hc.NewBackground("bg", nil, 0, 20*time.Millisecond, time.Second, func(ctx context.Context) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		return io.EOF
+	})
+	hcInst := hcWithChecks(t, check)
+	getCalls := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return calls
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	requireNoError(t, hcInst.Pause("bg", "maintenance"))
+	time.Sleep(10 * time.Millisecond) // let the in-flight iteration finish
+	callsOnPause := getCalls()
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireReportEqual(t, hc.Report{
+		Status: hc.StatusUp,
+		Checks: []hc.Check{
+			{
+				Name:     "bg",
+				State:    hc.CheckState{ActualAt: timeNow, Status: hc.StatusPaused, Error: "paused: maintenance"},
+				Previous: res.Checks[0].Previous,
+			},
+		},
+	}, res)
+
+	time.Sleep(50 * time.Millisecond)
+	requireTrue(t, getCalls() == callsOnPause, "paused check should not run")
+
+	requireNoError(t, hcInst.Resume("bg"))
+	time.Sleep(50 * time.Millisecond)
+	requireTrue(t, getCalls() > callsOnPause, "resumed check should run")
+
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Checks[0].State.Status == hc.StatusDown, "resumed check should report actual state")
+}
+
+func TestBackgroundPausedStatus(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewBackground("bg", nil, time.Hour, time.Hour, time.Second, func(ctx context.Context) error { return nil },
+		hc.WithPausedStatus(hc.StatusDown))
+	hcInst := hcWithChecks(t, check)
+
+	check.Pause("maintenance")
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "configured status should be used")
+	requireTrue(t, res.Checks[0].Stats.SinceChange == 0, "not observed check should have zero since_change, got %s",
+		res.Checks[0].Stats.SinceChange)
+
+	err := hcInst.Pause("unknown", "")
+	requireTrue(t, errors.Is(err, hc.ErrCheckNotFound), "unexpected error: %v", err)
+}
+
+func TestManualHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	check := hc.NewManual("worker", hc.WithHeartbeatTTL(50*time.Millisecond))
+	hcInst := hcWithChecks(t, check)
+
+	check.SetErr(nil)
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusUp, "fresh heartbeat should be up")
+
+	time.Sleep(100 * time.Millisecond)
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "expired heartbeat should be down")
+	requireTrue(t, strings.HasPrefix(res.Checks[0].State.Error, "heartbeat expired: last heartbeat was "),
+		"unexpected error: %s", res.Checks[0].State.Error)
+
+	check.Beat()
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusUp, "beat should bring the check back")
+
+	check.SetErr(io.EOF)
+	check.Beat()
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "beat should not reset the error")
+}
+
+func TestMetadataAndDetails(t *testing.T) {
+	t.Parallel()
+
+	hcInst, err := hc.New()
+	requireNoError(t, err)
+
+	lag := 0.0
+	check := hc.NewBasic("replica", time.Second, func(context.Context) error {
+		if lag > 10 {
+			return hc.Detailed(errors.New("replication lag is too high"), map[string]any{"lag_seconds": lag})
+		}
+
+		return hc.Detailed(nil, map[string]any{"lag_seconds": lag})
+	})
+	requireNoError(t, hcInst.Register(context.Background(), check, hc.WithMetadata(map[string]string{"owner": "dba"})))
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusUp, "details without error should be up")
+	requireTrue(t, res.Checks[0].Metadata["owner"] == "dba", "metadata should be reported")
+	requireTrue(t, res.Checks[0].State.Details["lag_seconds"] == 0.0, "details should be reported")
+
+	lag = 42
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "details with error should be down")
+	requireTrue(t, res.Checks[0].State.Error == "replication lag is too high", "unexpected error: %s", res.Checks[0].State.Error)
+	requireTrue(t, res.Checks[0].State.Details["lag_seconds"] == 42.0, "details should be reported")
+	requireTrue(t, res.Checks[0].Previous[0].Details["lag_seconds"] == 0.0, "details should be kept in history")
+}
+
+func TestTagsFilter(t *testing.T) {
+	t.Parallel()
+
+	hcInst, err := hc.New(hc.WithSingleflight())
+	requireNoError(t, err)
+
+	requireNoError(t, hcInst.Register(context.Background(), simpleCheck("postgres", nil), hc.WithTags("db")))
+	requireNoError(t, hcInst.Register(context.Background(), simpleCheck("redis", io.EOF), hc.WithTags("cache"), hc.WithNonCritical()))
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDegraded, "unexpected status: %s", res.Status)
+	requireTrue(t, len(res.Checks[0].Tags) == 1 && res.Checks[0].Tags[0] == "db", "tags should be reported")
+
+	res = hcInst.RunAllChecks(context.Background(), hc.WithExclude("cache"))
+	requireTrue(t, res.Status == hc.StatusUp, "unexpected status: %s", res.Status)
+	requireTrue(t, len(res.Checks) == 1 && res.Checks[0].Name == "postgres", "only postgres expected")
+
+	res = hcInst.RunAllChecks(context.Background(), hc.WithInclude("redis"))
+	requireTrue(t, len(res.Checks) == 1 && res.Checks[0].Name == "redis", "only redis expected")
+}
+
+func TestDependencies(t *testing.T) {
+	t.Parallel()
+
+	hcInst, err := hc.New(hc.WithMaxConcurrency(1))
+	requireNoError(t, err)
+
+	postgres := hc.NewManual("postgres")
+	calls := 0
+	schema := hc.NewBasic("billing_schema", time.Second, func(context.Context) error {
+		calls++
+
+		return nil
+	})
+
+	requireNoError(t, hcInst.Register(context.Background(), postgres))
+	requireNoError(t, hcInst.Register(context.Background(), schema, hc.WithDependsOn("postgres")))
+	requireNoError(t, hcInst.Register(context.Background(), simpleCheck("billing_api", nil), hc.WithDependsOn("billing_schema")))
+
+	res := hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "unexpected status: %s", res.Status)
+	requireStateEqual(t, hc.CheckState{Status: hc.StatusSkipped, Error: "skipped due to postgres"}, res.Checks[1].State)
+	requireStateEqual(t, hc.CheckState{Status: hc.StatusSkipped, Error: "skipped due to billing_schema"}, res.Checks[2].State)
+	requireTrue(t, calls == 0, "skipped check should not be run")
+
+	postgres.SetErr(nil)
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusUp, "unexpected status: %s", res.Status)
+	requireTrue(t, calls == 1, "check should be run when dependency is up")
+
+	// Dependency is resolved even when it is excluded from the report.
+	postgres.SetErr(io.EOF)
+	res = hcInst.RunAllChecks(context.Background(), hc.WithExclude("postgres"))
+	requireTrue(t, len(res.Checks) == 2, "excluded dependency should not be reported")
+	requireStateEqual(t, hc.CheckState{Status: hc.StatusSkipped, Error: "skipped due to postgres"}, res.Checks[0].State)
+
+	err = hcInst.Unregister("postgres")
+	requireTrue(t, errors.Is(err, hc.ErrHasDependents), "unexpected error: %v", err)
+	postgres.SetErr(nil)
+
+	// Critical check should not be hidden behind the non-critical dependency.
+	requireNoError(t, hcInst.Register(context.Background(), simpleCheck("cache", io.EOF), hc.WithNonCritical()))
+	requireNoError(t, hcInst.Register(context.Background(), simpleCheck("orders", io.EOF), hc.WithDependsOn("cache")))
+	res = hcInst.RunAllChecks(context.Background())
+	requireTrue(t, res.Status == hc.StatusDown, "unexpected status: %s", res.Status)
+	requireStateEqual(t, hc.CheckState{Status: hc.StatusDown, Error: "EOF"}, helpFindCheck(t, res.Checks, "orders").State)
+
+	err = hcInst.Register(context.Background(), simpleCheck("orphan", nil), hc.WithDependsOn("unknown"))
+	requireTrue(t, errors.Is(err, hc.ErrCheckNotFound), "unexpected error: %v", err)
+}
+
 func TestShutdown(t *testing.T) {
 	t.Parallel()
 
@@ -1,67 +1,235 @@
 package logr
 
 import (
-	"container/ring"
 	"sync"
 	"time"
 )
 
-const maxStatesToStore = 5
+// DefaultSize is the number of records stored by default.
+const DefaultSize = 5
 
+// Ring stores the last records of the check. The number of records is limited by size, and optionally by age of
+// records. The last record is always kept, because it is the actual state of the check.
 type Ring struct {
-	mu   *sync.RWMutex
-	data *ring.Ring
+	mu          *sync.RWMutex
+	size        int
+	retention   time.Duration
+	transitions bool
+	data        []Rec // oldest first
+
+	// failures and successes are the numbers of failed and passed records in a row. They are not limited by size.
+	failures  int
+	successes int
 }
 
 func New() *Ring {
 	return &Ring{
-		mu:   new(sync.RWMutex),
-		data: ring.New(maxStatesToStore),
+		mu:          new(sync.RWMutex),
+		size:        DefaultSize,
+		retention:   0,
+		transitions: false,
+		data:        make([]Rec, 0, DefaultSize),
 	}
 }
 
-func (r *Ring) Put(rec Rec) {
+// SetLimits changes the max number of records to store and the max age of stored records. Non-positive size means
+// default size. Zero retention means that records are not limited by age.
+func (r *Ring) SetLimits(size int, retention time.Duration) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.data.Value = rec
-	r.data = r.data.Prev()
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	r.size = size
+	r.retention = retention
+	r.trim()
+}
+
+// SetTransitionsOnly enables the mode when only state transitions are stored. A record with the same status and
+// error text as the last one will update the last record instead of adding a new one.
+func (r *Ring) SetTransitionsOnly(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transitions = enabled
+}
+
+// Put stores the record and returns it as it was stored.
+func (r *Ring) Put(rec Rec) Rec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec.FirstSeen.IsZero() {
+		rec.FirstSeen = rec.Time
+	}
+
+	if rec.Count == 0 {
+		rec.Count = 1
+	}
+
+	if rec.Error != nil {
+		r.failures += rec.Count
+		r.successes = 0
+	} else {
+		r.successes += rec.Count
+		r.failures = 0
+	}
+
+	rec.Failures = r.failures
+	rec.Successes = r.successes
+
+	if r.transitions && len(r.data) != 0 {
+		last := &r.data[len(r.data)-1]
+		if sameState(*last, rec) {
+			last.Time = rec.Time
+			last.Error = rec.Error
+			last.Duration = rec.Duration
+			last.Details = rec.Details
+			last.Count += rec.Count
+			last.Failures = rec.Failures
+			last.Successes = rec.Successes
+
+			return *last
+		}
+	}
+
+	r.data = append(r.data, rec)
+	r.trim()
+
+	return rec
 }
 
 func (r *Ring) GetLast() (Rec, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	last := r.data.Next()
-
-	if last.Value == nil {
+	if len(r.data) == 0 {
 		return Rec{}, false
 	}
 
-	return last.Value.(Rec), true
+	return r.data[len(r.data)-1], true
 }
 
+// SlicePrev returns all records except the last one. Newest first.
 func (r *Ring) SlicePrev() []Rec {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	res := make([]Rec, 0, r.data.Len())
-	r.data.Do(func(val any) {
-		if val == nil {
-			return
+	since := r.since()
+
+	res := make([]Rec, 0, len(r.data))
+	for i := len(r.data) - 2; i >= 0; i-- {
+		if r.data[i].Time.Before(since) {
+			break
 		}
 
-		res = append(res, val.(Rec))
-	})
+		res = append(res, r.data[i])
+	}
 
 	if len(res) == 0 {
 		return nil
 	}
 
-	return res[1:]
+	return res
+}
+
+// since returns the time before which records are expired. Should be called under lock.
+func (r *Ring) since() time.Time {
+	if r.retention <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(-r.retention)
+}
+
+// trim removes records that are out of limits. Should be called under write lock.
+func (r *Ring) trim() {
+	drop := max(len(r.data)-r.size, 0)
+
+	since := r.since()
+	for drop < len(r.data)-1 && r.data[drop].Time.Before(since) {
+		drop++
+	}
+
+	if drop == 0 {
+		return
+	}
+
+	r.data = append(r.data[:0], r.data[drop:]...)
 }
 
 type Rec struct {
+	// Time is the time of the record. For collapsed records it is the time of the last occurrence.
 	Time  time.Time
 	Error error
+	// Duration is the time spent by the check.
+	Duration time.Duration
+	// Cached is true when the record is reused instead of running the check again.
+	Cached bool
+	// FirstSeen is the time of the first occurrence of collapsed record. See Ring.SetTransitionsOnly.
+	FirstSeen time.Time
+	// Count is the number of occurrences of collapsed record.
+	Count int
+	// Failures and Successes are the numbers of failed and passed records in a row up to this record, including the
+	// records that are not stored anymore.
+	Failures  int
+	Successes int
+	// Details are the structured details of the result, like replication lag or queue depth.
+	Details map[string]any
+}
+
+// sameState returns true when both records have the same status and the same error text.
+func sameState(a, b Rec) bool {
+	if a.Error == nil || b.Error == nil {
+		return a.Error == nil && b.Error == nil
+	}
+
+	return a.Error.Error() == b.Error.Error()
+}
+
+// Transitions returns the number of status changes (ok <-> error) between records that were made not earlier than
+// since. Records should be sorted by time in any direction.
+func Transitions(recs []Rec, since time.Time) int {
+	res := 0
+	for i := 1; i < len(recs); i++ {
+		if recs[i-1].Time.Before(since) || recs[i].Time.Before(since) {
+			continue
+		}
+
+		if (recs[i-1].Error == nil) != (recs[i].Error == nil) {
+			res++
+		}
+	}
+
+	return res
+}
+
+// SuccessRatio returns the ratio of successful occurrences within records that were made not earlier than since.
+// Occurrences of collapsed records are distributed evenly between FirstSeen and Time. Returns false when there are no
+// occurrences within the window.
+func SuccessRatio(recs []Rec, since time.Time) (float64, bool) {
+	var total, success float64
+	for _, rec := range recs {
+		if rec.Time.Before(since) {
+			continue
+		}
+
+		count := float64(max(rec.Count, 1))
+		if rec.Count > 1 && rec.FirstSeen.Before(since) {
+			count *= float64(rec.Time.Sub(since)) / float64(rec.Time.Sub(rec.FirstSeen))
+		}
+
+		total += count
+		if rec.Error == nil {
+			success += count
+		}
+	}
+
+	if total == 0 {
+		return 0, false
+	}
+
+	return success / total, true
 }
@@ -2,75 +2,699 @@ package healthcheck
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/kazhuravlev/healthcheck/internal/logr"
 	"github.com/kazhuravlev/just"
+	"log/slog"
+	"regexp"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
-func (s *Healthcheck) runCheck(ctx context.Context, check checkContainer) Check {
-	ctx, cancel := context.WithTimeout(ctx, check.Check.timeout())
+// checkState keeps the reported state of the registered check between runs.
+type checkState struct {
+	mu        *sync.Mutex
+	lastRecAt time.Time
+	status    Status
+	changedAt time.Time
+	failures  int
+	successes int
+}
+
+func newCheckState() *checkState {
+	return &checkState{
+		mu:        new(sync.Mutex),
+		lastRecAt: time.Time{},
+		status:    "",
+		changedAt: time.Time{},
+		failures:  0,
+		successes: 0,
+	}
+}
+
+// observation is the result of checkState.observe.
+type observation struct {
+	status    Status
+	changedAt time.Time
+	failures  int
+}
+
+// observe accounts the record and returns the status that should be reported. Records with the same time are
+// counted once, because manual and background checks return the same record until the next run. Stored records of
+// built-in checks carry the number of attempts in a row, so all attempts between reports are taken into account.
+func (s *checkState) observe(rec logr.Rec, opts checkOptions) observation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !rec.Time.Equal(s.lastRecAt) {
+		s.lastRecAt = rec.Time
+		switch {
+		case rec.Count > 0:
+			// Built-in checks count their attempts by themselves, because they can run many times between reports.
+			s.failures = rec.Failures
+			s.successes = rec.Successes
+		case rec.Error != nil:
+			s.failures++
+			s.successes = 0
+		default:
+			s.successes++
+			s.failures = 0
+		}
+	}
+
+	prevStatus := s.status
+	switch {
+	case s.status == "":
+		s.status = StatusUp
+		if rec.Error != nil {
+			s.status = StatusDown
+		}
+	case s.status == StatusUp && s.failures >= opts.failureThreshold:
+		s.status = StatusDown
+	case s.status == StatusDown && s.successes >= opts.successThreshold:
+		s.status = StatusUp
+	}
+
+	if s.status != prevStatus {
+		s.changedAt = rec.Time
+	}
+
+	return observation{
+		status:    s.status,
+		changedAt: s.changedAt,
+		failures:  s.failures,
+	}
+}
+
+// validate returns the error when the check can not be registered. Should be called under checksMu.
+func (s *Healthcheck) validate(check ICheck, options checkOptions) error {
+	if err := validateOptions(check, options); err != nil {
+		return err
+	}
+
+	if err := s.validateStrict(check); err != nil {
+		return err
+	}
+
+	return s.validateDependencies(check, options)
+}
+
+// validateOptions returns ErrInvalidOption when options of the check can not be applied.
+func validateOptions(check ICheck, options checkOptions) error {
+	if options.flapWindow > 0 && options.flapHold != StatusUp && options.flapHold != StatusDown {
+		return fmt.Errorf("register %q: flap detection hold %q: %w", check.ID(), options.flapHold, ErrInvalidOption)
+	}
+
+	return nil
+}
+
+// validateStrict returns the error when the check can not be registered in strict mode. See WithStrictNames. Should
+// be called under checksMu.
+func (s *Healthcheck) validateStrict(check ICheck) error {
+	if !s.opts.strictNames {
+		return nil
+	}
+
+	checkID, ok := name2id(check.ID())
+	if !ok || !isValidID(checkID) {
+		return fmt.Errorf("register %q: %w", check.ID(), ErrInvalidName)
+	}
+
+	if s.indexOf(checkID) != -1 {
+		return fmt.Errorf("register %q: %w", check.ID(), ErrDuplicateName)
+	}
+
+	return nil
+}
+
+// validateDependencies returns ErrCheckNotFound when any dependency of the check is not registered. See WithDependsOn.
+// Should be called under checksMu.
+func (s *Healthcheck) validateDependencies(check ICheck, options checkOptions) error {
+	for _, parent := range options.dependsOn {
+		if s.indexOf(parent) == -1 {
+			return fmt.Errorf("register %q: dependency %q: %w", check.ID(), parent, ErrCheckNotFound)
+		}
+	}
+
+	return nil
+}
+
+// indexOf returns the index of the check with given id or -1. Should be called under checksMu.
+func (s *Healthcheck) indexOf(id string) int {
+	return slices.IndexFunc(s.checks, func(c checkContainer) bool { return c.ID == id })
+}
+
+// getBackground returns the registered background check by id.
+func (s *Healthcheck) getBackground(id string) (*bgCheck, error) {
+	s.checksMu.RLock()
+	defer s.checksMu.RUnlock()
+
+	idx := s.indexOf(id)
+	if idx == -1 {
+		return nil, ErrCheckNotFound
+	}
+
+	check, ok := s.checks[idx].Check.(*bgCheck)
+	if !ok {
+		return nil, ErrNotBackgroundCheck
+	}
+
+	return check, nil
+}
+
+// setupHistory applies history options to built-in checks.
+func (s *Healthcheck) setupHistory(check ICheck, options checkOptions) {
+	holder, ok := check.(historyHolder)
+	if !ok {
+		return
+	}
+
+	size, retention := logr.DefaultSize, time.Duration(0)
+	switch {
+	case options.historySize > 0 || options.historyRetention > 0:
+		size, retention = options.historySize, options.historyRetention
+	case s.opts.historySize > 0 || s.opts.historyRetention > 0:
+		size, retention = s.opts.historySize, s.opts.historyRetention
+	}
+
+	if size <= 0 {
+		size = logr.DefaultSize
+	}
+
+	// Flap detection needs at least transitions+1 changes of the state, so transitions+2 records.
+	if options.flapWindow > 0 {
+		size = max(size, options.flapTransitions+2)
+	}
+
+	holder.history().SetLimits(size, retention)
+
+	if options.historyTransitions {
+		holder.history().SetTransitionsOnly(true)
+	}
+}
+
+// startCheck starts background activity of the check, if any. Returns the function that stops it or nil when the
+// check has nothing to stop. Should be called under checksMu.
+func (s *Healthcheck) startCheck(ctx context.Context, check ICheck) context.CancelFunc {
+	if !s.isRunning {
+		return nil
+	}
+
+	switch check := check.(type) {
+	case *bgCheck:
+		ctx, cancel := context.WithCancel(ctx)
+		check.run(ctx, s.opts.logger, s.bgWG)
+
+		return cancel
+	default:
+		return nil
+	}
+}
+
+// stop stops background activity of the check. Should be called under checksMu.
+func (c *checkContainer) stop() {
+	if c.Stop != nil {
+		c.Stop()
+		c.Stop = nil
+	}
+}
+
+// current returns the last observation without accounting a new record.
+func (s *checkState) current() observation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return observation{
+		status:    s.status,
+		changedAt: s.changedAt,
+		failures:  s.failures,
+	}
+}
+
+func (s *Healthcheck) runAllChecks(ctx context.Context, opts runOptions) Report {
+	s.checksMu.RLock()
+	checksCopy, hidden := s.selectChecks(opts)
+	isShuttingDown := s.isShuttingDown
+	s.checksMu.RUnlock()
+
+	checks := make([]Check, len(checksCopy))
+	{
+		if s.opts.reportTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.opts.reportTimeout)
+			defer cancel()
+		}
+
+		var sem chan struct{}
+		if s.opts.maxConcurrency > 0 {
+			sem = make(chan struct{}, s.opts.maxConcurrency)
+		}
+
+		wg := new(sync.WaitGroup)
+		wg.Add(len(checksCopy))
+
+		done := make([]chan struct{}, len(checksCopy))
+		for i := range done {
+			done[i] = make(chan struct{})
+		}
+
+		// TODO(zhuravlev): do not run goroutines for checks like manual and bg check.
+		for i := range checksCopy {
+			go func(i int, check checkContainer) {
+				defer wg.Done()
+				defer close(done[i])
+
+				if parent := failedParent(check, checksCopy[:i], checks, done); parent != "" {
+					checks[i] = s.buildCheck(check, logr.Rec{Time: time.Now(), Error: &skippedError{parent: parent}})
+					return
+				}
+
+				release := func() {}
+				if sem != nil {
+					select {
+					case <-ctx.Done():
+						// The check has no chance to be started before the deadline.
+						checks[i] = s.buildCheck(check, logr.Rec{Time: time.Now(), Error: ctx.Err()})
+						return
+					case sem <- struct{}{}:
+						// The slot is released only when the check returns, even if it ignores ctx.
+						release = func() { <-sem }
+					}
+				}
+
+				checks[i] = s.runCheck(ctx, check, release)
+			}(i, checksCopy[i])
+		}
+
+		wg.Wait()
+	}
+
+	// Dependencies that do not match the filter are run only to decide on their dependents.
+	for i := len(checks) - 1; i >= 0; i-- {
+		if hidden[i] {
+			checks = slices.Delete(checks, i, i+1)
+		}
+	}
+
+	// The include filter that matches nothing is most likely a typo in the probe. It should not pass silently.
+	if len(checks) == 0 && opts.strictInclude() {
+		checks = append(checks, Check{
+			Name:     "__no_matching_checks__",
+			Critical: true,
+			State: CheckState{
+				ActualAt: time.Now(),
+				Status:   StatusDown,
+				Error:    "No checks match the filter",
+			},
+			Previous: nil,
+		})
+	}
+
+	// Shutdown is about readiness. The application that is shutting down is still alive.
+	if isShuttingDown && !opts.liveness {
+		checks = append(checks, Check{
+			Name:     "__shutting_down__",
+			Critical: true,
+			State: CheckState{
+				ActualAt: time.Now(),
+				Status:   StatusDown,
+				Error:    "The application in shutting down process",
+			},
+			Previous: nil,
+		})
+	}
+	status := StatusUp
+	for _, check := range checks {
+		if check.State.Status != StatusDown {
+			continue
+		}
+
+		if check.Critical {
+			status = StatusDown
+			break
+		}
+
+		status = StatusDegraded
+	}
+
+	return Report{
+		Status: status,
+		Checks: checks,
+	}
+}
+
+// selectChecks returns checks that match the filter together with their dependencies. Dependencies that do not match
+// the filter are marked as hidden. Should be called under checksMu.
+func (s *Healthcheck) selectChecks(opts runOptions) ([]checkContainer, []bool) {
+	const (
+		skip = iota
+		hide
+		show
+	)
+
+	// Dependencies are always registered before dependents, so one pass from the end is enough.
+	marks := make([]int, len(s.checks))
+	for i := len(s.checks) - 1; i >= 0; i-- {
+		if opts.match(s.checks[i]) {
+			marks[i] = show
+		}
+
+		if marks[i] == skip {
+			continue
+		}
+
+		for _, parent := range s.checks[i].Opts.dependsOn {
+			if idx := s.indexOf(parent); idx != -1 && marks[idx] == skip {
+				marks[idx] = hide
+			}
+		}
+	}
+
+	checks := make([]checkContainer, 0, len(s.checks))
+	hidden := make([]bool, 0, len(s.checks))
+	for i := range s.checks {
+		if marks[i] != skip {
+			checks = append(checks, s.checks[i])
+			hidden = append(hidden, marks[i] == hide)
+		}
+	}
+
+	return checks, hidden
+}
+
+// failedParent waits for dependencies of the check and returns the name of the first one that is down or skipped.
+// Only checks that are registered before the check are taken into account, so dependencies can not form a cycle.
+func failedParent(check checkContainer, before []checkContainer, checks []Check, done []chan struct{}) string {
+	for _, parent := range check.Opts.dependsOn {
+		idx := slices.IndexFunc(before, func(c checkContainer) bool { return c.ID == parent })
+		if idx == -1 {
+			continue
+		}
+
+		<-done[idx]
+
+		// Skipped check does not affect the status of the report. So the critical check can not be hidden behind the
+		// non-critical dependency.
+		if check.Opts.critical && !checks[idx].Critical {
+			continue
+		}
+
+		if status := checks[idx].State.Status; status == StatusDown || status == StatusSkipped {
+			return parent
+		}
+	}
+
+	return ""
+}
+
+// inflightRun is a run of all checks that is shared between concurrent callers. See WithSingleflight.
+type inflightRun struct {
+	done   chan struct{}
+	report Report
+}
+
+// result returns a copy of the report to not share the slice of checks between callers.
+func (r *inflightRun) result() Report {
+	return Report{
+		Status: r.report.Status,
+		Checks: slices.Clone(r.report.Checks),
+	}
+}
+
+// runCheck runs the check and returns its report entry. release is called when the call of the check returns, which
+// can be later than runCheck returns when the check does not respect the timeout.
+func (s *Healthcheck) runCheck(ctx context.Context, check checkContainer, release func()) Check {
+	ctx, cancel := context.WithTimeout(ctx, check.Check.Timeout())
 	defer cancel()
 
+	start := time.Now()
 	rec := logr.Rec{
-		Time:  time.Now(),
+		Time:  start,
 		Error: nil,
 	}
 
 	{
 		resCh := make(chan logr.Rec, 1)
 		go func() {
+			defer release()
 			defer close(resCh)
-			resCh <- check.Check.check(ctx)
+			defer func() {
+				if val := recover(); val != nil {
+					resCh <- logr.Rec{
+						Time:     start,
+						Error:    newPanicError(val),
+						Duration: time.Since(start),
+					}
+				}
+			}()
+
+			resCh <- check.Check.Check(ctx).logRec()
 		}()
 
 		select {
 		case <-ctx.Done():
 			rec = logr.Rec{
-				Time:  time.Now(),
-				Error: ctx.Err(),
+				Time:     time.Now(),
+				Error:    ctx.Err(),
+				Duration: time.Since(start),
 			}
 		case rec = <-resCh:
 		}
 	}
 
+	// Log only panics that happened during this run. Background checks log their panics by themselves.
+	var panicErr *PanicError
+	if errors.As(rec.Error, &panicErr) && !rec.Time.Before(start) {
+		s.opts.logger.ErrorContext(ctx, "check panicked",
+			slog.String("check", check.ID),
+			slog.String("error", panicErr.Error()))
+	}
+
+	return s.buildCheck(check, rec)
+}
+
+// buildCheck converts the actual record of the check into the report entry.
+func (s *Healthcheck) buildCheck(check checkContainer, rec logr.Rec) Check {
+	log := just.SliceMap(check.Check.Log(), Rec.logRec)
+
+	var (
+		obs      observation
+		status   Status
+		flapping bool
+		recs     = append([]logr.Rec{rec}, log...)
+		paused   *pausedError
+		skipped  *skippedError
+	)
+	switch {
+	case errors.As(rec.Error, &paused):
+		// Paused check is not running, so its state should not affect thresholds, flapping and stats.
+		obs = check.State.current()
+		status = paused.status
+		recs = log
+	case errors.As(rec.Error, &skipped):
+		// The same for the check that was skipped because of its dependency.
+		obs = check.State.current()
+		status = StatusSkipped
+		recs = log
+	default:
+		obs = check.State.observe(rec, check.Opts)
+		status = obs.status
+		flapping = isFlapping(recs, check.Opts)
+		if flapping {
+			status = check.Opts.flapHold
+		}
+	}
+
+	prev := just.SliceMap(log, rec2state)
+
+	state := rec2state(rec)
+	state.Status = status
+	if rec.Cached {
+		state.Age = time.Since(rec.Time)
+	}
+
+	// TODO(zhuravlev): run on manual and bg checks.
+	s.opts.setCheckStatus(check.ID, status)
+	s.opts.setCheckState(check.ID, state)
+
+	var schedule *ScheduleInfo
+	if check, ok := check.Check.(scheduled); ok {
+		schedule = check.scheduleInfo()
+	}
+
+	return Check{
+		Name:      check.ID,
+		Critical:  check.Opts.critical,
+		Flapping:  flapping,
+		State:     state,
+		Previous:  prev,
+		Stats:     buildStats(recs, obs),
+		Schedule:  schedule,
+		Metadata:  check.Opts.metadata,
+		Tags:      check.Opts.tags,
+		DependsOn: check.Opts.dependsOn,
+	}
+}
+
+// statsWindows are the windows of CheckStats success ratios.
+var statsWindows = [...]time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+
+// buildStats calculates statistics of the check based on its records. Records should be sorted from newest.
+func buildStats(recs []logr.Rec, obs observation) CheckStats {
+	now := time.Now()
+
+	var ratios [len(statsWindows)]*float64
+	for i, window := range statsWindows {
+		if ratio, ok := logr.SuccessRatio(recs, now.Add(-window)); ok {
+			ratios[i] = &ratio
+		}
+	}
+
+	// Paused and skipped checks may have no observed status yet.
+	var sinceChange time.Duration
+	if !obs.changedAt.IsZero() {
+		sinceChange = now.Sub(obs.changedAt)
+	}
+
+	return CheckStats{
+		SuccessRatio1m:      ratios[0],
+		SuccessRatio5m:      ratios[1],
+		SuccessRatio1h:      ratios[2],
+		SinceChange:         sinceChange,
+		ConsecutiveFailures: obs.failures,
+	}
+}
+
+// rec2state converts the record into the state with the raw status.
+func rec2state(rec logr.Rec) CheckState {
 	status := StatusUp
 	errText := ""
 	if rec.Error != nil {
 		status = StatusDown
 		errText = rec.Error.Error()
+
+		var (
+			paused  *pausedError
+			skipped *skippedError
+		)
+		switch {
+		case errors.As(rec.Error, &paused):
+			status = paused.status
+		case errors.As(rec.Error, &skipped):
+			status = StatusSkipped
+		}
 	}
 
-	// TODO(zhuravlev): run on manual and bg checks.
-	s.opts.setCheckStatus(check.ID, status)
+	var firstSeen *time.Time
+	count := 0
+	if rec.Count > 1 {
+		firstSeen = &rec.FirstSeen
+		count = rec.Count
+	}
 
-	prev := just.SliceMap(check.Check.log(), func(rec logr.Rec) CheckState {
-		status := StatusUp
-		errText := ""
+	return CheckState{
+		ActualAt:  rec.Time,
+		Status:    status,
+		Error:     errText,
+		Duration:  rec.Duration,
+		Cached:    rec.Cached,
+		Age:       0,
+		Count:     count,
+		FirstSeen: firstSeen,
+		Details:   rec.Details,
+	}
+}
 
-		if rec.Error != nil {
-			errText = rec.Error.Error()
-			status = StatusDown
+// isFlapping returns true when the check changes the state too often. See WithFlapDetection.
+func isFlapping(recs []logr.Rec, opts checkOptions) bool {
+	if opts.flapWindow <= 0 {
+		return false
+	}
+
+	return logr.Transitions(recs, time.Now().Add(-opts.flapWindow)) > opts.flapTransitions
+}
+
+const panicStackLines = 20
+
+// callSafe calls the check function and converts panic into PanicError.
+func callSafe(ctx context.Context, fn CheckFn) (err error) {
+	defer func() {
+		if val := recover(); val != nil {
+			err = newPanicError(val)
 		}
+	}()
+
+	return fn(ctx)
+}
+
+// newRec converts the stored record of the built-in check into Rec.
+func newRec(rec logr.Rec) Rec {
+	return Rec{
+		Time:     rec.Time,
+		Error:    rec.Error,
+		Duration: rec.Duration,
+		stored:   rec,
+	}
+}
+
+// logRec returns the record with bookkeeping of built-in checks. Records of custom checks have no bookkeeping, only
+// details extracted from the error.
+func (r Rec) logRec() logr.Rec {
+	rec := r.stored
+	rec.Time = r.Time
+	rec.Duration = r.Duration
+	rec.Error = r.Error
+	if rec.Details == nil {
+		rec.Details, rec.Error = splitDetails(r.Error)
+	}
+
+	return rec
+}
 
-		return CheckState{
-			ActualAt: rec.Time,
-			Status:   status,
-			Error:    errText,
+// splitDetails extracts details of the result from the error. See Detailed.
+func splitDetails(err error) (map[string]any, error) {
+	var detailed *DetailedError
+	if !errors.As(err, &detailed) {
+		return nil, err
+	}
+
+	// Only Detailed itself can mark the result as passed. The wrapped one is the error with its own text.
+	if detailed.Err == nil && err == error(detailed) {
+		return detailed.Details, nil
+	}
+
+	return detailed.Details, err
+}
+
+// newPanicError should be called from the deferred function that recovered the panic.
+func newPanicError(val any) *PanicError {
+	lines := strings.Split(string(debug.Stack()), "\n")
+
+	// Skip the frames of debug.Stack, deferred function and runtime.
+	for i := range lines {
+		if strings.HasPrefix(lines[i], "panic(") {
+			lines = lines[min(i+2, len(lines)):]
+			break
 		}
-	})
+	}
 
-	return Check{
-		Name: check.ID,
-		State: CheckState{
-			ActualAt: rec.Time,
-			Status:   status,
-			Error:    errText,
-		},
-		Previous: prev,
+	return &PanicError{
+		Value: val,
+		Stack: strings.Join(lines[:min(panicStackLines, len(lines))], "\n"),
 	}
 }
 
+var validIDRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// isValidID returns true when id contains only lowercase letters, digits and lodash.
+func isValidID(id string) bool {
+	return validIDRe.MatchString(id)
+}
+
 func name2id(name string) (string, bool) {
 	id := strings.ReplaceAll(strings.ToLower(name), "-", "_")
 
@@ -0,0 +1,211 @@
+package healthcheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule defines when the background check runs. See WithSchedule.
+type Schedule interface {
+	// Next returns the time of the next run after t. Zero time means that there are no more runs.
+	Next(t time.Time) time.Time
+}
+
+// Every returns the schedule that runs the check with a fixed period from the previous run. This is the default
+// schedule of background checks. Non-positive period means that there are no runs by schedule.
+func Every(period time.Duration) Schedule {
+	return everySchedule{period: period}
+}
+
+type everySchedule struct {
+	period time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	if s.period <= 0 {
+		return time.Time{}
+	}
+
+	return t.Add(s.period)
+}
+
+// Aligned returns the schedule that runs the check at wall-clock times that are multiples of interval, shifted by
+// offset. Times are aligned to UTC. interval should be positive.
+//
+//	healthcheck.Aligned(5*time.Minute, 0)          // every 5 minutes on the minute
+//	healthcheck.Aligned(time.Hour, 30*time.Minute) // hourly at :30
+func Aligned(interval, offset time.Duration) Schedule {
+	if interval <= 0 {
+		return alignedSchedule{interval: 0, offset: 0}
+	}
+
+	return alignedSchedule{interval: interval, offset: offset % interval}
+}
+
+type alignedSchedule struct {
+	interval time.Duration
+	offset   time.Duration
+}
+
+func (s alignedSchedule) Next(t time.Time) time.Time {
+	if s.interval <= 0 {
+		return time.Time{}
+	}
+
+	next := t.Truncate(s.interval).Add(s.offset)
+	for !next.After(t) {
+		next = next.Add(s.interval)
+	}
+
+	return next
+}
+
+// ParseCron parses the standard cron expression with 5 fields: minute, hour, day of month, month and day of week.
+// Each field supports `*`, single values, ranges `a-b`, steps `*/n` and `a-b/n`, and lists separated by comma. Day
+// of week is 0-7, where both 0 and 7 are Sunday. Descriptors @hourly, @daily, @weekly, @monthly and @yearly are
+// supported too. Times are calculated in the location of the time passed to Next.
+//
+//	sched, err := healthcheck.ParseCron("30 * * * *") // hourly at :30
+func ParseCron(expr string) (Schedule, error) {
+	switch strings.TrimSpace(expr) {
+	case "@hourly":
+		expr = "0 * * * *"
+	case "@daily":
+		expr = "0 0 * * *"
+	case "@weekly":
+		expr = "0 0 * * 0"
+	case "@monthly":
+		expr = "0 0 1 * *"
+	case "@yearly":
+		expr = "0 0 1 1 *"
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 { //nolint:gomnd
+		return nil, fmt.Errorf("parse cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var sets [5]uint64
+	for i := range fields {
+		set, err := parseCronField(fields[i], bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("parse cron %q: field %q: %w", expr, fields[i], err)
+		}
+
+		sets[i] = set
+	}
+
+	// Sunday can be written as 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var res uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepStr)
+			}
+		}
+
+		from, to := lo, hi
+		if rng != "*" {
+			fromStr, toStr, isRange := strings.Cut(rng, "-")
+
+			var err error
+			from, err = strconv.Atoi(fromStr)
+			if err != nil {
+				return 0, fmt.Errorf("bad value %q", fromStr)
+			}
+
+			to = from
+			switch {
+			case isRange:
+				to, err = strconv.Atoi(toStr)
+				if err != nil {
+					return 0, fmt.Errorf("bad value %q", toStr)
+				}
+			case hasStep:
+				to = hi
+			}
+		}
+
+		if from < lo || to > hi || from > to {
+			return 0, fmt.Errorf("value out of range [%d, %d]", lo, hi)
+		}
+
+		for i := from; i <= to; i += step {
+			res |= 1 << uint(i)
+		}
+	}
+
+	return res, nil
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// cronSearchLimit limits the search of the next time for expressions that never match, like "0 0 30 2 *".
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows the cron rule: when both day of month and day of week are restricted, any of them should match.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dowMatch
+	case s.dowStar:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
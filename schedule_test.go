@@ -0,0 +1,149 @@
+package healthcheck_test
+
+import (
+	"context"
+	"github.com/kazhuravlev/healthcheck"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 5, 10, 12, 3, 17, 0, time.UTC)
+	require.Equal(t, start.Add(time.Minute), healthcheck.Every(time.Minute).Next(start))
+	require.True(t, healthcheck.Every(0).Next(start).IsZero())
+
+	require.Panics(t, func() {
+		healthcheck.NewBackground("zero", nil, 0, 0, time.Second, func(context.Context) error { return nil })
+	})
+}
+
+func TestAligned(t *testing.T) {
+	t.Parallel()
+
+	at := func(h, m, s int) time.Time { return time.Date(2024, 5, 10, h, m, s, 0, time.UTC) }
+
+	table := []struct {
+		name     string
+		interval time.Duration
+		offset   time.Duration
+		in       time.Time
+		exp      time.Time
+	}{
+		{"five_minutes", 5 * time.Minute, 0, at(12, 3, 17), at(12, 5, 0)},
+		{"exactly_on_boundary", 5 * time.Minute, 0, at(12, 5, 0), at(12, 10, 0)},
+		{"hourly_with_offset", time.Hour, 30 * time.Minute, at(12, 3, 17), at(12, 30, 0)},
+		{"hourly_with_offset_passed", time.Hour, 30 * time.Minute, at(12, 31, 0), at(13, 30, 0)},
+		{"offset_bigger_than_interval", time.Hour, 90 * time.Minute, at(12, 3, 17), at(12, 30, 0)},
+		{"invalid_interval", 0, 0, at(12, 3, 17), time.Time{}},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, row.exp, healthcheck.Aligned(row.interval, row.offset).Next(row.in))
+		})
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+
+	// 2024-05-10 is Friday.
+	start := time.Date(2024, 5, 10, 12, 3, 17, 0, time.UTC)
+
+	table := []struct {
+		expr string
+		exp  time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 10, 12, 4, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 5, 10, 12, 15, 0, 0, time.UTC)},
+		{"30 * * * *", time.Date(2024, 5, 10, 12, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 5, 10, 13, 0, 0, 0, time.UTC)},
+		{"0,5 3 * * *", time.Date(2024, 5, 11, 3, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 1", time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		{"@hourly", time.Date(2024, 5, 10, 13, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, row := range table {
+		t.Run(row.expr, func(t *testing.T) {
+			t.Parallel()
+
+			sched, err := healthcheck.ParseCron(row.expr)
+			require.NoError(t, err)
+			require.Equal(t, row.exp, sched.Next(start))
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *", "@every"} {
+			_, err := healthcheck.ParseCron(expr)
+			require.Error(t, err, expr)
+		}
+	})
+}
+
+func TestBackgroundSchedule(t *testing.T) {
+	t.Parallel()
+
+	sched, err := healthcheck.ParseCron("0 0 1 1 *")
+	require.NoError(t, err)
+
+	hc, _ := healthcheck.New(healthcheck.WithManualStart())
+	calls := new(atomic.Int32)
+	check := healthcheck.NewBackground("yearly", nil, 0, 0, time.Second, func(ctx context.Context) error {
+		calls.Add(1)
+
+		return nil
+	}, healthcheck.WithSchedule(sched))
+	require.NoError(t, hc.Register(context.Background(), check))
+
+	hc.Start(context.Background())
+	defer hc.Close(context.Background())
+
+	require.Eventually(t, func() bool {
+		report := hc.RunAllChecks(context.Background())
+
+		return report.Checks[0].Schedule != nil && report.Checks[0].Schedule.NextRunAt.Equal(sched.Next(time.Now()))
+	}, time.Second, 10*time.Millisecond)
+
+	// The first run should happen by the schedule, not right after the delay.
+	report := hc.RunAllChecks(context.Background())
+	require.Equal(t, int32(0), calls.Load())
+	require.Greater(t, report.Checks[0].Schedule.Interval, 364*24*time.Hour)
+}
+
+func TestBackgroundScheduleEvery(t *testing.T) {
+	t.Parallel()
+
+	hc, _ := healthcheck.New(healthcheck.WithManualStart())
+	check := healthcheck.NewBackground("every", nil, time.Hour, 0, time.Second, func(ctx context.Context) error {
+		return nil
+	}, healthcheck.WithSchedule(healthcheck.Every(time.Minute)))
+	require.NoError(t, hc.Register(context.Background(), check))
+
+	hc.Start(context.Background())
+	defer hc.Close(context.Background())
+
+	require.Eventually(t, func() bool {
+		return hc.RunAllChecks(context.Background()).Checks[0].Schedule != nil
+	}, time.Second, 10*time.Millisecond)
+
+	// The interval should be taken from the schedule, not from the period of the check.
+	report := hc.RunAllChecks(context.Background())
+	require.Equal(t, time.Minute, report.Checks[0].Schedule.Interval)
+}
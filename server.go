@@ -6,8 +6,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -32,8 +34,8 @@ func NewServer(hc IHealthcheck, opts ...func(*serverOptions)) (*Server, error) {
 func (s *Server) Run(ctx context.Context) error {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/live", LiveHandler())
-	mux.HandleFunc("/ready", ReadyHandler(s.opts.healthcheck))
+	mux.HandleFunc("/live", LivenessHandler(s.opts.healthcheck, s.opts.handlerOpts...))
+	mux.HandleFunc("/ready", ReadyHandler(s.opts.healthcheck, s.opts.handlerOpts...))
 	mux.Handle("/metrics", promhttp.Handler())
 
 	httpServer := &http.Server{
@@ -61,7 +63,8 @@ func (s *Server) Run(ctx context.Context) error {
 	return nil
 }
 
-// LiveHandler return an implementation of /live request.
+// LiveHandler return an implementation of /live request that always returns 200. Use LivenessHandler to take
+// liveness checks into account.
 func LiveHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -69,15 +72,38 @@ func LiveHandler() http.HandlerFunc {
 	}
 }
 
+// LivenessHandler build a http.HandlerFunc from liveness checks of healthcheck. See WithLiveness. The response has the
+// same shape as the response of ReadyHandler.
+func LivenessHandler(healthcheck IHealthcheck, opts ...func(*handlerOptions)) http.HandlerFunc {
+	return reportHandler(func(req *http.Request) Report {
+		runOpts := append([]RunOption{WithLivenessChecks()}, filterFromQuery(req.URL.Query())...)
+
+		return healthcheck.RunAllChecks(req.Context(), runOpts...)
+	}, opts)
+}
+
 // ReadyHandler build a http.HandlerFunc from healthcheck.
-func ReadyHandler(healthcheck IHealthcheck) http.HandlerFunc {
+func ReadyHandler(healthcheck IHealthcheck, opts ...func(*handlerOptions)) http.HandlerFunc {
+	return reportHandler(func(req *http.Request) Report {
+		return healthcheck.RunAllChecks(req.Context(), filterFromQuery(req.URL.Query())...)
+	}, opts)
+}
+
+// reportHandler writes the report returned by run.
+func reportHandler(run func(req *http.Request) Report, opts []func(*handlerOptions)) http.HandlerFunc {
+	options := handlerOptions{
+		degradedStatusCode: http.StatusOK,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return func(w http.ResponseWriter, req *http.Request) {
 		const unknownResp = `{"status":"unknown","checks":[]}`
 
-		ctx := req.Context()
 		w.Header().Set("Content-Type", "application/json")
 
-		report := healthcheck.RunAllChecks(ctx)
+		report := run(req)
 		reportJson, err := json.Marshal(report)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -92,9 +118,40 @@ func ReadyHandler(healthcheck IHealthcheck) http.HandlerFunc {
 		case StatusUp:
 			w.WriteHeader(http.StatusOK)
 			_, _ = w.Write(reportJson)
+		case StatusDegraded:
+			w.WriteHeader(options.degradedStatusCode)
+			_, _ = w.Write(reportJson)
 		case StatusDown:
 			w.WriteHeader(http.StatusInternalServerError)
 			_, _ = w.Write(reportJson)
 		}
 	}
 }
+
+// filterFromQuery returns options of RunAllChecks from `include` and `exclude` query params. Both can be repeated or
+// contain names and tags separated by comma, like `/ready?exclude=etcd,cache`.
+func filterFromQuery(query url.Values) []RunOption {
+	var opts []RunOption
+	if include := splitQuery(query["include"]); len(include) != 0 {
+		opts = append(opts, WithInclude(include...))
+	}
+
+	if exclude := splitQuery(query["exclude"]); len(exclude) != 0 {
+		opts = append(opts, WithExclude(exclude...))
+	}
+
+	return opts
+}
+
+func splitQuery(values []string) []string {
+	var res []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				res = append(res, name)
+			}
+		}
+	}
+
+	return res
+}
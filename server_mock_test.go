@@ -41,15 +41,20 @@ func (m *MockIHealthcheck) EXPECT() *MockIHealthcheckMockRecorder {
 }
 
 // RunAllChecks mocks base method.
-func (m *MockIHealthcheck) RunAllChecks(arg0 context.Context) healthcheck.Report {
+func (m *MockIHealthcheck) RunAllChecks(arg0 context.Context, arg1 ...healthcheck.RunOption) healthcheck.Report {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RunAllChecks", arg0)
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RunAllChecks", varargs...)
 	ret0, _ := ret[0].(healthcheck.Report)
 	return ret0
 }
 
 // RunAllChecks indicates an expected call of RunAllChecks.
-func (mr *MockIHealthcheckMockRecorder) RunAllChecks(arg0 any) *gomock.Call {
+func (mr *MockIHealthcheckMockRecorder) RunAllChecks(arg0 any, arg1 ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunAllChecks", reflect.TypeOf((*MockIHealthcheck)(nil).RunAllChecks), arg0)
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunAllChecks", reflect.TypeOf((*MockIHealthcheck)(nil).RunAllChecks), varargs...)
 }
@@ -9,6 +9,11 @@ type serverOptions struct {
 	port        int
 	healthcheck IHealthcheck
 	logger      ILogger
+	handlerOpts []func(*handlerOptions)
+}
+
+type handlerOptions struct {
+	degradedStatusCode int
 }
 
 type ILogger interface {
@@ -17,7 +22,7 @@ type ILogger interface {
 }
 
 type IHealthcheck interface {
-	RunAllChecks(ctx context.Context) Report
+	RunAllChecks(ctx context.Context, opts ...RunOption) Report
 }
 
 func WithLogger(logger *slog.Logger) func(o *serverOptions) {
@@ -37,3 +42,17 @@ func WithHealthcheck(hc *Healthcheck) func(o *serverOptions) {
 		o.healthcheck = hc
 	}
 }
+
+// WithHandlerOptions will pass options to handlers of the server.
+func WithHandlerOptions(opts ...func(*handlerOptions)) func(o *serverOptions) {
+	return func(o *serverOptions) {
+		o.handlerOpts = append(o.handlerOpts, opts...)
+	}
+}
+
+// WithDegradedStatusCode sets the http status code for reports with StatusDegraded. Default is 200.
+func WithDegradedStatusCode(code int) func(o *handlerOptions) {
+	return func(o *handlerOptions) {
+		o.degradedStatusCode = code
+	}
+}
@@ -2,6 +2,8 @@ package healthcheck_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"github.com/kazhuravlev/healthcheck"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -54,9 +56,83 @@ func TestReadyHandler(t *testing.T) {
 
 	f(healthcheck.StatusDown, http.StatusInternalServerError, `{"status":"down","checks":[]}`)
 
+	f(healthcheck.StatusDegraded, http.StatusOK, `{"status":"degraded","checks":[]}`)
+
 	f("i_do_not_know", http.StatusInternalServerError, `{"status":"unknown","checks":[]}`)
 }
 
+func TestReadyHandlerDegradedStatusCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	hc := NewMockIHealthcheck(ctrl)
+
+	hc.
+		EXPECT().
+		RunAllChecks(gomock.Any()).
+		Return(healthcheck.Report{
+			Status: healthcheck.StatusDegraded,
+			Checks: []healthcheck.Check{},
+		})
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	w := httptest.NewRecorder()
+
+	handler := healthcheck.ReadyHandler(hc, healthcheck.WithDegradedStatusCode(http.StatusServiceUnavailable))
+	handler(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
+
+func TestReadyHandlerFilter(t *testing.T) {
+	hcInst, err := healthcheck.New()
+	require.NoError(t, err)
+
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return io.EOF }
+	require.NoError(t, hcInst.Register(context.Background(), healthcheck.NewBasic("postgres", time.Second, ok), healthcheck.WithTags("db")))
+	require.NoError(t, hcInst.Register(context.Background(), healthcheck.NewBasic("etcd", time.Second, fail), healthcheck.WithTags("db")))
+	require.NoError(t, hcInst.Register(context.Background(), healthcheck.NewBasic("stripe", time.Second, ok), healthcheck.WithTags("external")))
+
+	handler := healthcheck.ReadyHandler(hcInst)
+
+	table := []struct {
+		url      string
+		expCode  int
+		expNames []string
+	}{
+		{"/ready", http.StatusInternalServerError, []string{"postgres", "etcd", "stripe"}},
+		{"/ready?exclude=etcd", http.StatusOK, []string{"postgres", "stripe"}},
+		{"/ready?include=db&exclude=etcd", http.StatusOK, []string{"postgres"}},
+		{"/ready?include=external,postgres", http.StatusOK, []string{"postgres", "stripe"}},
+		{"/ready?exclude=db&exclude=external", http.StatusOK, nil},
+		{"/ready?include=dbb", http.StatusInternalServerError, []string{"__no_matching_checks__"}},
+	}
+
+	for _, row := range table {
+		t.Run(row.url, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, row.url, nil)
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			var report healthcheck.Report
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+
+			var names []string
+			for _, check := range report.Checks {
+				names = append(names, check.Name)
+			}
+
+			require.Equal(t, row.expCode, res.StatusCode)
+			require.Equal(t, row.expNames, names)
+		})
+	}
+}
+
 func TestLiveHandler(t *testing.T) {
 	handler := healthcheck.LiveHandler()
 
@@ -69,6 +145,54 @@ func TestLiveHandler(t *testing.T) {
 	require.NoError(t, res.Body.Close())
 }
 
+func TestLivenessHandler(t *testing.T) {
+	hcInst, err := healthcheck.New()
+	require.NoError(t, err)
+
+	hcInst.Shutdown()
+	require.NoError(t, hcInst.Register(context.Background(), healthcheck.NewBasic("postgres", time.Second, func(context.Context) error {
+		return io.EOF
+	})))
+
+	loop := healthcheck.NewManual("event_loop")
+	loop.SetErr(nil)
+	require.NoError(t, hcInst.Register(context.Background(), loop, healthcheck.WithLiveness()))
+
+	handler := healthcheck.LivenessHandler(hcInst)
+	live := func() (int, healthcheck.Report) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/live", nil))
+
+		res := w.Result()
+		defer res.Body.Close()
+
+		var report healthcheck.Report
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+
+		return res.StatusCode, report
+	}
+
+	code, report := live()
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, report.Checks, 1)
+	require.Equal(t, "event_loop", report.Checks[0].Name)
+
+	loop.SetErr(errors.New("event loop is stuck"))
+	code, report = live()
+	require.Equal(t, http.StatusInternalServerError, code)
+	require.Equal(t, healthcheck.StatusDown, report.Status)
+
+	// Filters should not fail liveness, even when nothing matches.
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/live?include=unknown&exclude=event_loop", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	ready := hcInst.RunAllChecks(context.Background())
+	for _, check := range ready.Checks {
+		require.NotEqual(t, "event_loop", check.Name)
+	}
+}
+
 func TestServer(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	hc := NewMockIHealthcheck(ctrl)
@@ -84,6 +208,14 @@ func TestServer(t *testing.T) {
 	time.Sleep(time.Second)
 
 	t.Run("live_returns_200", func(t *testing.T) {
+		hc.
+			EXPECT().
+			RunAllChecks(gomock.Any(), gomock.Any()).
+			Return(healthcheck.Report{
+				Status: healthcheck.StatusUp,
+				Checks: []healthcheck.Check{},
+			})
+
 		req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(port)+"/live", nil)
 		require.NoError(t, err)
 
@@ -2,6 +2,7 @@ package healthcheck
 
 import (
 	"context"
+	"fmt"
 	"github.com/kazhuravlev/healthcheck/internal/logr"
 	"time"
 )
@@ -11,18 +12,70 @@ type Status string
 const (
 	StatusUp   Status = "up"
 	StatusDown Status = "down"
+	// StatusDegraded means that all critical checks are up, but at least one non-critical check is down.
+	StatusDegraded Status = "degraded"
+	// StatusPaused is the status of paused background check. It does not affect the status of the report.
+	StatusPaused Status = "paused"
+	// StatusSkipped is the status of the check that was not run because its dependency is down. It does not affect the
+	// status of the report. See WithDependsOn.
+	StatusSkipped Status = "skipped"
 )
 
 type CheckState struct {
 	ActualAt time.Time `json:"actual_at"`
 	Status   Status    `json:"status"`
 	Error    string    `json:"error"`
+	// Duration is the time spent by the check. It is zero for manual checks.
+	Duration time.Duration `json:"duration"`
+	// Cached is true when the state was reused from the previous run. See WithCacheTTL.
+	Cached bool `json:"cached,omitempty"`
+	// Age is the age of the cached state.
+	Age time.Duration `json:"age,omitempty"`
+	// Count is the number of repeats of the same state. It is filled when the state was repeated, see
+	// WithTransitionHistory. ActualAt is the time of the last repeat in this case.
+	Count int `json:"count,omitempty"`
+	// FirstSeen is the time of the first repeat of the state.
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+	// Details are the structured details of the result. See Detailed.
+	Details map[string]any `json:"details,omitempty"`
 }
 
 type Check struct {
 	Name     string       `json:"name"`
+	Critical bool         `json:"critical"`
+	Flapping bool         `json:"flapping,omitempty"`
 	State    CheckState   `json:"state"`
 	Previous []CheckState `json:"previous"`
+	Stats    CheckStats   `json:"stats"`
+	// Schedule is filled for background checks.
+	Schedule *ScheduleInfo `json:"schedule,omitempty"`
+	// Metadata is the static information about the check. See WithMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Tags are the tags of the check. See WithTags.
+	Tags []string `json:"tags,omitempty"`
+	// DependsOn are the names of checks this check depends on. See WithDependsOn.
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// ScheduleInfo describes the schedule of the background check.
+type ScheduleInfo struct {
+	// Interval is the current interval between runs. It grows while the check is failing, see WithBackoff.
+	Interval  time.Duration `json:"interval"`
+	NextRunAt time.Time     `json:"next_run_at"`
+}
+
+// CheckStats contains rolling statistics of the check. Success ratios are calculated from the stored history of the
+// check, so they are nil when there are no records within the window. Configure the history (see WithHistory and
+// WithTransitionHistory) to cover the windows you are interested in.
+type CheckStats struct {
+	SuccessRatio1m *float64 `json:"success_ratio_1m"`
+	SuccessRatio5m *float64 `json:"success_ratio_5m"`
+	SuccessRatio1h *float64 `json:"success_ratio_1h"`
+	// SinceChange is the time since the last change of the reported status. It is zero when the status was not
+	// observed yet, like for the check that was paused before the first run.
+	SinceChange time.Duration `json:"since_change"`
+	// ConsecutiveFailures is the number of failed attempts in a row.
+	ConsecutiveFailures int `json:"consecutive_failures"`
 }
 
 type Report struct {
@@ -32,14 +85,97 @@ type Report struct {
 
 type CheckFn func(ctx context.Context) error
 
+// PanicError is the error of the check that panicked. Stack contains an excerpt of the stack trace.
+type PanicError struct {
+	Value any
+	Stack string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// DetailedError carries structured details of the check result. Nil Err means that the check is passed, but only when
+// DetailedError is returned as is. Wrapped DetailedError is the failure of the check. See Detailed.
+type DetailedError struct {
+	Err     error
+	Details map[string]any
+}
+
+func (e *DetailedError) Error() string {
+	if e.Err == nil {
+		return "details without error"
+	}
+
+	return e.Err.Error()
+}
+
+func (e *DetailedError) Unwrap() error { return e.Err }
+
+// Detailed attaches details to the result of the check function. The details are reported in the state of the check.
+//
+//	return healthcheck.Detailed(err, map[string]any{"replication_lag": lag.Seconds()})
+func Detailed(err error, details map[string]any) error {
+	return &DetailedError{Err: err, Details: details}
+}
+
+// Rec is a single result of the check. Nil Error means that the check is passed. Use Detailed to attach details to
+// the result.
+type Rec struct {
+	Time  time.Time
+	Error error
+	// Duration is the time spent by the check.
+	Duration time.Duration
+
+	// stored is the record of built-in checks with its bookkeeping, like cache and repeats.
+	stored logr.Rec
+}
+
+// ICheck is a check that can be registered in Healthcheck. Implement it to create your own kind of check.
 type ICheck interface {
-	id() string
-	check(ctx context.Context) logr.Rec
-	timeout() time.Duration
-	log() []logr.Rec
+	// ID returns the name of the check. See Healthcheck.Register for naming rules.
+	ID() string
+	// Check returns the actual state of the check. It is called on each RunAllChecks.
+	Check(ctx context.Context) Rec
+	// Timeout returns the max duration of Check call.
+	Timeout() time.Duration
+	// Log returns previous results of the check (newest first) without the actual one.
+	Log() []Rec
+}
+
+// historyHolder is implemented by built-in checks that store their history in logr.Ring.
+type historyHolder interface {
+	history() *logr.Ring
+}
+
+// scheduled is implemented by checks that run by schedule.
+type scheduled interface {
+	scheduleInfo() *ScheduleInfo
+}
+
+// pausedError is the error of paused background check.
+type pausedError struct {
+	reason string
+	status Status
+}
+
+func (e *pausedError) Error() string {
+	return "paused: " + e.reason
+}
+
+// skippedError is the error of the check that was skipped because its dependency is down.
+type skippedError struct {
+	parent string
+}
+
+func (e *skippedError) Error() string {
+	return "skipped due to " + e.parent
 }
 
 type checkContainer struct {
 	ID    string
 	Check ICheck
+	Opts  checkOptions
+	State *checkState
+	Stop  context.CancelFunc
 }